@@ -0,0 +1,309 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+const (
+	// FormatVersion is bumped whenever the bundle layout changes
+	FormatVersion = 1
+
+	Extension    = ".tgz"
+	ManifestFile = "manifest.json"
+	filesPrefix  = "template/"
+
+	// MaxSize bounds how much data is read from a bundle
+	MaxSize = 256 << 20
+)
+
+// Manifest describes a bundle and the checksum of every file in it.
+type Manifest struct {
+	FormatVersion int    `json:"format_version"`
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Description   string `json:"description,omitempty"`
+	Files         []File `json:"files"`
+}
+
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Bundle is a template archive read into memory.
+type Bundle struct {
+	Manifest Manifest
+	files    map[string][]byte
+}
+
+// FileName returns the conventional file name for a bundle of tmpl.
+func FileName(tmpl *config.Template) string {
+	if tmpl.Version == "" {
+		return tmpl.Metadata.Name + Extension
+	}
+	return fmt.Sprintf("%s-%s%s", tmpl.Metadata.Name, tmpl.Version, Extension)
+}
+
+// Pack writes templateDir as a gzipped tar bundle to w. Entries are written
+// in a fixed order with fixed timestamps so the same template always
+// produces the same bytes.
+func Pack(templateDir string, tmpl *config.Template, w io.Writer) (*Manifest, error) {
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		Name:          tmpl.Metadata.Name,
+		Version:       tmpl.Version,
+		Description:   tmpl.Metadata.Description,
+	}
+	contents := make(map[string][]byte)
+
+	err := filepath.WalkDir(templateDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("cannot pack %s: only regular files are supported", filePath)
+		}
+
+		relativePath, err := filepath.Rel(templateDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+
+		name := filepath.ToSlash(relativePath)
+		contents[name] = content
+		manifest.Files = append(manifest.Files, File{
+			Path:   name,
+			Size:   int64(len(content)),
+			SHA256: checksum(content),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := contents[config.TemplateConfigFile]; !ok {
+		return nil, fmt.Errorf("'%s' has no %s", templateDir, config.TemplateConfigFile)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := writeEntry(tarWriter, ManifestFile, manifestData); err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		if err := writeEntry(tarWriter, filesPrefix+file.Path, contents[file.Path]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func writeEntry(w *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Unix(0, 0),
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	}
+	if err := w.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Read loads a bundle and verifies it against its manifest.
+func Read(r io.Reader) (*Bundle, error) {
+	gzipReader, err := gzip.NewReader(io.LimitReader(r, MaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("not a template bundle: %w", err)
+	}
+	defer gzipReader.Close()
+
+	bundle := &Bundle{files: make(map[string][]byte)}
+	var manifestData []byte
+	var total int64
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle entry %s is not a regular file", header.Name)
+		}
+
+		total += header.Size
+		if total > MaxSize {
+			return nil, fmt.Errorf("bundle is larger than %d bytes", MaxSize)
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+
+		if header.Name == ManifestFile {
+			manifestData = content
+			continue
+		}
+
+		name, ok := strings.CutPrefix(header.Name, filesPrefix)
+		if !ok {
+			return nil, fmt.Errorf("unexpected bundle entry %s", header.Name)
+		}
+		if err := validatePath(name); err != nil {
+			return nil, err
+		}
+		bundle.files[name] = content
+	}
+
+	if manifestData == nil {
+		return nil, fmt.Errorf("bundle has no %s", ManifestFile)
+	}
+	if err := json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestFile, err)
+	}
+
+	if err := bundle.Verify(); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// Verify checks that the bundle holds exactly the files in its manifest and
+// that every checksum matches.
+func (bundle *Bundle) Verify() error {
+	manifest := bundle.Manifest
+
+	if manifest.FormatVersion != FormatVersion {
+		return fmt.Errorf("unsupported bundle format version %d (expected %d)", manifest.FormatVersion, FormatVersion)
+	}
+	if config.ValidateTemplateName(manifest.Name) != nil {
+		return fmt.Errorf("bundle has an invalid template name '%s'", manifest.Name)
+	}
+
+	listed := make(map[string]bool)
+	for _, file := range manifest.Files {
+		if err := validatePath(file.Path); err != nil {
+			return err
+		}
+
+		content, ok := bundle.files[file.Path]
+		if !ok {
+			return fmt.Errorf("bundle is missing %s", file.Path)
+		}
+		if int64(len(content)) != file.Size || checksum(content) != file.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", file.Path)
+		}
+		listed[file.Path] = true
+	}
+
+	for name := range bundle.files {
+		if !listed[name] {
+			return fmt.Errorf("bundle file %s is not listed in the manifest", name)
+		}
+	}
+
+	if !listed[config.TemplateConfigFile] {
+		return fmt.Errorf("bundle has no %s", config.TemplateConfigFile)
+	}
+
+	return nil
+}
+
+// Extract writes the bundle's files into dir, which must not exist yet.
+func (bundle *Bundle) Extract(dir string) error {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
+	}
+
+	for _, file := range bundle.Manifest.Files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(target, bundle.files[file.Path], 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// validatePath rejects entries that could escape the install directory.
+func validatePath(name string) error {
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || clean != name || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(name, "\\") {
+		return fmt.Errorf("bundle contains an unsafe path '%s'", name)
+	}
+	return nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Checksum returns the sha256 of a whole bundle file, as accepted by
+// tg install --checksum.
+func Checksum(data []byte) string {
+	return "sha256:" + checksum(data)
+}
+
+// ReadBytes is Read for a bundle already held in memory.
+func ReadBytes(data []byte) (*Bundle, error) {
+	return Read(bytes.NewReader(data))
+}
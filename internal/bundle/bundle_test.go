@@ -0,0 +1,134 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "template.toml"},
+		{name: "src/main.go"},
+		{name: ".gitignore"},
+		{name: "a..b/c"},
+		{name: "", wantErr: true},
+		{name: ".", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "../evil", wantErr: true},
+		{name: "src/../../evil", wantErr: true},
+		{name: "src/../main.go", wantErr: true},
+		{name: "./main.go", wantErr: true},
+		{name: "src//main.go", wantErr: true},
+		{name: "src/", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: `..\evil`, wantErr: true},
+		{name: `src\main.go`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePath(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPackRead(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		config.TemplateConfigFile: "version = \"1.2.0\"\n",
+		"src/main.go":             "package main\n",
+	}
+	for name, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl := &config.Template{Version: "1.2.0"}
+	tmpl.Metadata.Name = "web"
+
+	var data bytes.Buffer
+	if _, err := Pack(dir, tmpl, &data); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	bundle, err := ReadBytes(data.Bytes())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	installed := filepath.Join(t.TempDir(), "web")
+	if err := bundle.Extract(installed); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	for name, want := range files {
+		content, err := os.ReadFile(filepath.Join(installed, filepath.FromSlash(name)))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v, want %q", name, content, err, want)
+		}
+	}
+}
+
+// rawBundle builds a bundle whose manifest lists every entry as is, without
+// the checks Pack makes.
+func rawBundle(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	manifest := Manifest{FormatVersion: FormatVersion, Name: "web", Version: "1.0.0"}
+	for name, content := range entries {
+		manifest.Files = append(manifest.Files, File{Path: name, Size: int64(len(content)), SHA256: checksum([]byte(content))})
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data bytes.Buffer
+	gzipWriter := gzip.NewWriter(&data)
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err := writeEntry(tarWriter, ManifestFile, manifestData); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range entries {
+		if err := writeEntry(tarWriter, filesPrefix+name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+func TestReadRejectsUnsafePaths(t *testing.T) {
+	for _, name := range []string{"../evil", "/etc/passwd", "src/../../evil", `..\evil`} {
+		t.Run(name, func(t *testing.T) {
+			data := rawBundle(t, map[string]string{
+				config.TemplateConfigFile: "version = \"1.0.0\"\n",
+				name:                      "evil\n",
+			})
+			_, err := ReadBytes(data)
+			if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+				t.Errorf("Read() error = %v, want an unsafe path error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+const fetchTimeout = 60 * time.Second
+
+// IsURL reports whether source should be downloaded rather than read from disk.
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Fetch reads raw bundle bytes from a local path or an http(s) URL.
+func Fetch(source string) ([]byte, error) {
+	if !IsURL(source) {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open bundle: %w", err)
+		}
+		defer file.Close()
+		return readLimited(file)
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	response, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download bundle: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download bundle: %s returned %s", source, response.Status)
+	}
+	return readLimited(response.Body)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("bundle is larger than %d bytes", MaxSize)
+	}
+	return data, nil
+}
+
+// Install extracts the bundle into templatesDir under the template's name.
+// An existing template is only replaced when force is set, and is restored
+// if the new one cannot be moved into place.
+func (bundle *Bundle) Install(templatesDir, name string, force bool) (string, error) {
+	if name == "" {
+		name = bundle.Manifest.Name
+	}
+	if err := config.ValidateTemplateName(name); err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(templatesDir, name)
+	_, err := os.Stat(target)
+	exists := err == nil
+	if exists && !force {
+		return "", fmt.Errorf("template '%s' already exists. Use --force to replace it", target)
+	}
+
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	staging, err := os.MkdirTemp(templatesDir, ".tg-install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	extracted := filepath.Join(staging, "template")
+	if err := bundle.Extract(extracted); err != nil {
+		return "", err
+	}
+
+	previous := filepath.Join(staging, "previous")
+	if exists {
+		if err := os.Rename(target, previous); err != nil {
+			return "", fmt.Errorf("failed to replace %s: %w", target, err)
+		}
+	}
+
+	if err := os.Rename(extracted, target); err != nil {
+		if exists {
+			os.Rename(previous, target)
+		}
+		return "", fmt.Errorf("failed to install template: %w", err)
+	}
+
+	return target, nil
+}
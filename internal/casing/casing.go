@@ -0,0 +1,81 @@
+// Package casing converts identifiers between naming conventions such as
+// camelCase, PascalCase, snake_case and kebab-case.
+package casing
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits text into lower-case words at separators, case changes and
+// the end of acronyms: "HTTPServer_v2" gives [http server v2].
+func Words(text string) []string {
+	var (
+		words   []string
+		current []rune
+	)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// "fooBar" splits before B, "HTTPServer" before the S
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// Camel returns text in camelCase.
+func Camel(text string) string {
+	words := Words(text)
+	for i := 1; i < len(words); i++ {
+		words[i] = capitalize(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// Pascal returns text in PascalCase.
+func Pascal(text string) string {
+	words := Words(text)
+	for i := range words {
+		words[i] = capitalize(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// Snake returns text in snake_case.
+func Snake(text string) string {
+	return strings.Join(Words(text), "_")
+}
+
+// Kebab returns text in kebab-case.
+func Kebab(text string) string {
+	return strings.Join(Words(text), "-")
+}
+
+func capitalize(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,67 @@
+package casing
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: nil},
+		{text: "Payments", want: []string{"payments"}},
+		{text: "PAYMENTS", want: []string{"payments"}},
+		{text: "payments_svc", want: []string{"payments", "svc"}},
+		{text: "payments-api", want: []string{"payments", "api"}},
+		{text: "payments api", want: []string{"payments", "api"}},
+		{text: "__payments--svc__", want: []string{"payments", "svc"}},
+		{text: "paymentsApi", want: []string{"payments", "api"}},
+		{text: "PaymentsAPI", want: []string{"payments", "api"}},
+		{text: "HTTPServer", want: []string{"http", "server"}},
+		{text: "HTTPServer_v2", want: []string{"http", "server", "v2"}},
+		{text: "userID", want: []string{"user", "id"}},
+		{text: "v2Api", want: []string{"v2", "api"}},
+		{text: "base64Encode", want: []string{"base64", "encode"}},
+		{text: "ipv4", want: []string{"ipv4"}},
+		{text: "ÜberCafé", want: []string{"über", "café"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Words(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Words(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		text                        string
+		camel, pascal, snake, kebab string
+	}{
+		{text: "payments_svc", camel: "paymentsSvc", pascal: "PaymentsSvc", snake: "payments_svc", kebab: "payments-svc"},
+		{text: "PAYMENTS", camel: "payments", pascal: "Payments", snake: "payments", kebab: "payments"},
+		{text: "HTTPServer", camel: "httpServer", pascal: "HttpServer", snake: "http_server", kebab: "http-server"},
+		{text: "api v2", camel: "apiV2", pascal: "ApiV2", snake: "api_v2", kebab: "api-v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Camel(tt.text); got != tt.camel {
+				t.Errorf("Camel() = %q, want %q", got, tt.camel)
+			}
+			if got := Pascal(tt.text); got != tt.pascal {
+				t.Errorf("Pascal() = %q, want %q", got, tt.pascal)
+			}
+			if got := Snake(tt.text); got != tt.snake {
+				t.Errorf("Snake() = %q, want %q", got, tt.snake)
+			}
+			if got := Kebab(tt.text); got != tt.kebab {
+				t.Errorf("Kebab() = %q, want %q", got, tt.kebab)
+			}
+		})
+	}
+}
@@ -2,42 +2,124 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Naviary-Sanctuary/template_generator/internal/config"
-	"github.com/Naviary-Sanctuary/template_generator/internal/template"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
 	"github.com/spf13/cobra"
 )
 
 var (
-	applyOutputPath string
-	applyVariables  map[string]string
+	applyOutputPath  string
+	applyVariables   map[string]string
+	applyVarFiles    map[string]string
+	applyVarEnvs     map[string]string
+	applyJobs        int
+	applyReport      string
+	applyArchive     string
+	applyStdout      string
+	applyDryRun      bool
+	applyStrict      bool
+	applyNoManifest  bool
+	applyWatch       bool
+	applyPreview     bool
+	applyInteractive bool
+	applyAllowHooks  bool
 )
 
+// streamOnlyFile is the --stdout value used when no path is given.
+const streamOnlyFile = "-"
+
 func newApplyCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apply <template-name> [output-dir]",
 		Short: "Apply a template to generate files",
 		Long: `Apply reads a template and generates files by substituting variables.
 
-Variables use their default values defined in template.toml.
-The output directory defaults to the current directory if not specified.`,
+Variables use their default values defined in template.toml, overridden by
+[defaults] from the user and project config, and finally by --var,
+--var-file and --var-env. Use the latter two for secrets, so they do not end
+up in shell history; secret variables are redacted in verbose output and
+left out of the manifest.
+The output directory defaults to the current directory if not specified.
+
+When writing to a directory, apply records the template, its version, the
+variables and a hash of every generated file in .tg-manifest.json so that
+'tg status' can later report what changed.
+
+The pre_apply and post_apply hooks of a template are shell commands. They
+are only run with --allow-hooks; check them with 'tg info' first.`,
 		Example: `  # Apply template to current directory
   tg apply hello-world
 
   # Apply template to specific directory
-  tg apply hello-world ./my-project`,
+  tg apply hello-world ./my-project
+
+  # Print a JSON report of every generated file
+  tg apply hello-world ./my-project --output json
+
+  # Package the result instead of writing it
+  tg apply hello-world --archive my-project.zip
+
+  # Print one generated file
+  tg apply hello-world --stdout=README.md
+
+  # Show what would change without writing anything
+  tg apply hello-world ./my-project --dry-run -V
+
+  # Read a token from a file and another from the environment
+  tg apply npm-package ./pkg --var-file npm_token=.npm-token --var-env registry_password=REGISTRY_PASSWORD
+
+  # Answer the template's questions instead of passing --var
+  tg apply web-app ./my-app -i
+
+  # Re-render on every change while editing the template
+  tg apply hello-world ./my-project --watch`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: runApply,
 	}
 
-	cmd.Flags().StringVarP(&applyOutputPath, "output", "o", ".", "Output directory")
+	cmd.Flags().StringVarP(&applyOutputPath, "output-dir", "o", ".", "Output directory")
 	cmd.Flags().StringToStringVarP(&applyVariables, "var", "v", nil, "Set variable values (e.g. -v name=John -v age=30)")
+	cmd.Flags().StringToStringVar(&applyVarFiles, "var-file", nil, "Read a variable's value from a file (e.g. --var-file token=./token.txt)")
+	cmd.Flags().StringToStringVar(&applyVarEnvs, "var-env", nil, "Read a variable's value from an environment variable (e.g. --var-env token=NPM_TOKEN)")
+	cmd.Flags().StringVar(&applyReport, "report", "", "Print a report of every file instead of the summary: json, yaml (same as --output)")
+	cmd.Flags().IntVarP(&applyJobs, "jobs", "j", 0, "Number of files to render concurrently (default: number of CPUs)")
+	cmd.Flags().StringVar(&applyArchive, "archive", "", "Write the files to a .zip, .tar or .tar.gz archive instead of the output directory")
+	cmd.Flags().StringVar(&applyStdout, "stdout", "", "Write one generated file to stdout instead of the output directory (--stdout=<path>, or --stdout for a single-file template)")
+	cmd.Flags().Lookup("stdout").NoOptDefVal = streamOnlyFile
+	cmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Render in memory and report what would change without writing anything")
+	cmd.Flags().BoolVar(&applyStrict, "strict", false, "Fail on references to undefined variables instead of rendering <no value>")
+	cmd.Flags().BoolVar(&applyNoManifest, "no-manifest", false, "Do not record the generated files in "+manifest.FileName)
+	cmd.Flags().BoolVar(&applyAllowHooks, "allow-hooks", false, "Run the template's pre_apply and post_apply shell commands")
+	cmd.Flags().BoolVarP(&applyInteractive, "interactive", "i", false, "Ask for each variable not set with --var, in declaration order")
+	cmd.Flags().BoolVarP(&applyWatch, "watch", "w", false, "Render again whenever the template changes, until interrupted")
+	cmd.Flags().BoolVar(&applyPreview, "preview", false, "With --watch, render into a temporary directory instead of the output directory")
 
 	return cmd
 }
 
+// legacyApplyOutput keeps 'tg apply --output <dir>' working. Before --output
+// became the global output format it named apply's output directory, so a
+// value that is not a format is taken as the directory, with a warning.
+func legacyApplyOutput(cmd *cobra.Command) {
+	if cmd.Name() != "apply" || validateOutputFormat() == nil || cmd.Flags().Changed("output-dir") {
+		return
+	}
+
+	WarnColor.Fprintf(os.Stderr, "Warning: --output for the output directory is deprecated, use --output-dir or -o\n")
+	applyOutputPath = outputFormat
+	outputFormat = OutputText
+}
+
 func runApply(cmd *cobra.Command, args []string) error {
 	templateName := args[0]
 
@@ -45,90 +127,333 @@ func runApply(cmd *cobra.Command, args []string) error {
 		applyOutputPath = args[1]
 	}
 
-	InfoColor.Printf("Applying template: %s\n", BoldColor.Sprint(templateName))
+	reportFormat := applyReport
+	if reportFormat == "" && IsMachineOutput() {
+		reportFormat = outputFormat
+	}
+	if reportFormat != "" && reportFormat != OutputJSON && reportFormat != OutputYAML {
+		return fmt.Errorf("unsupported report format '%s' (supported: json, yaml)", reportFormat)
+	}
+
+	targets := 0
+	for _, set := range []bool{applyArchive != "", applyStdout != "", applyDryRun} {
+		if set {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return fmt.Errorf("--archive, --stdout and --dry-run cannot be used together")
+	}
+	if applyWatch && (targets > 0 || reportFormat != "") {
+		return fmt.Errorf("--watch writes to a directory and cannot be combined with --archive, --stdout, --dry-run or a report")
+	}
+	if applyPreview && !applyWatch {
+		return fmt.Errorf("--preview needs --watch")
+	}
+	if applyStdout != "" {
+		if reportFormat != "" {
+			return fmt.Errorf("--stdout cannot be combined with a %s report", reportFormat)
+		}
+		stdoutReserved = true
+	}
+
+	if reportFormat == "" && !stdoutReserved {
+		InfoColor.Printf("Applying template: %s\n", BoldColor.Sprint(templateName))
+	}
 
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	templateDir, tmpl, err := resolveTemplateDir(cfg, templateName)
+	chain, err := resolveTemplateChain(cfg, templateName)
 	if err != nil {
 		return err
 	}
+	templateDir, tmpl := chain.Dir(), chain.Template.Config
 
 	PrintVerbose("Template loaded: %s\n", tmpl.Metadata.Name)
 	PrintVerbose("Description: %s\n", tmpl.Metadata.Description)
+	if len(chain.Dirs) > 1 {
+		PrintVerbose("Extends: %s\n", strings.Join(chain.Names(), " <- "))
+	}
 
-	variables := make(map[string]any)
-	for name, variable := range tmpl.Variables {
-		variables[name] = variable.Default
+	overrides, err := variableOverrides(applyVariables, applyVarFiles, applyVarEnvs)
+	if err != nil {
+		return err
 	}
 
-	for key, value := range applyVariables {
-		variables[key] = value
+	if applyInteractive {
+		known := make(map[string]any)
+		for name, variable := range tmpl.Variables {
+			known[name] = variable.Default
+		}
+		maps.Copy(known, cfg.Defaults)
+		maps.Copy(known, overrides)
+
+		if overrides, err = promptVariables(os.Stdin, os.Stderr, tmpl.Variables, known, overrides); err != nil {
+			return err
+		}
 	}
 
-	for name, value := range variables {
-		PrintVerbose("Variable %s = %v\n", name, value)
+	if applyWatch {
+		return watchApply(cfg, chain, overrides)
 	}
 
-	if err := os.MkdirAll(applyOutputPath, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	variables, err := chain.Template.Resolve(cfg.Defaults, overrides)
+	if err != nil {
+		return err
+	}
+
+	printVariables(tmpl.Variables, variables)
+
+	if err := chain.Template.Parse(); err != nil {
+		return fmt.Errorf("failed to process template: %w", err)
 	}
 
-	processor := template.NewProcessor(tmpl, variables)
-	result, err := processor.Process(templateDir, applyOutputPath)
+	if applyJobs > 0 {
+		chain.Template.SetWorkers(applyJobs)
+	}
+	if applyStrict {
+		chain.Template.SetStrict(true)
+	}
+
+	// Hooks run arbitrary commands from the template, so they only run when
+	// asked for, and only when writing to disk since they change the
+	// working tree
+	toDisk := targets == 0
+	hooks := len(tmpl.Hooks.PreApply) + len(tmpl.Hooks.PostApply)
+	runHooks := toDisk && applyAllowHooks
+	switch {
+	case hooks > 0 && !toDisk:
+		PrintVerbose("Skipping hooks: output is not written to disk\n")
+	case hooks > 0 && !applyAllowHooks:
+		WarnColor.Fprintf(os.Stderr, "Skipping %d hook command(s) declared by the template. Review them with 'tg info %s' and pass --allow-hooks to run them\n", hooks, templateName)
+	}
+
+	// Hook output must not mix with a machine-readable report on stdout
+	hookOutput := os.Stdout
+	if reportFormat != "" {
+		hookOutput = os.Stderr
+	}
+
+	// Strict checks run before pre_apply hooks can change anything
+	if runHooks && (applyStrict || tmpl.Strict) && len(tmpl.Hooks.PreApply) > 0 {
+		if err := chain.Template.Check(variables); err != nil {
+			return fmt.Errorf("failed to process template: %w", err)
+		}
+	}
+
+	if runHooks {
+		if err := chain.Template.RunHooks(tmpl.Hooks.PreApply, ".", variables, hookOutput, os.Stderr); err != nil {
+			return fmt.Errorf("pre_apply %w", err)
+		}
+	}
+
+	result, destination, err := applyToTarget(chain.Template, variables)
 	if err != nil {
 		return fmt.Errorf("failed to process template: %w", err)
 	}
 
-	SuccessColor.Println("✓ Template applied successfully!")
-	PrintVerbose("  Output directory: %s\n", BoldColor.Sprint(applyOutputPath))
+	if toDisk && !applyNoManifest {
+		if err := recordManifest(applyOutputPath, chain.Template, variables, result); err != nil {
+			return err
+		}
+	}
+
+	if runHooks {
+		if err := chain.Template.RunHooks(tmpl.Hooks.PostApply, applyOutputPath, variables, hookOutput, os.Stderr); err != nil {
+			return fmt.Errorf("post_apply %w", err)
+		}
+	}
+
+	if reportFormat != "" {
+		return writeApplyReport(os.Stdout, reportFormat, templateDir, tmpl, destination, result)
+	}
+
+	switch {
+	case stdoutReserved:
+		return nil
+	case applyDryRun:
+		SuccessColor.Println("✓ Dry run complete, nothing was written")
+		fmt.Printf("  %d to create, %d to overwrite, %d to patch, %d unchanged\n",
+			result.Count(tg.ActionCreated)+result.Count(tg.ActionCopied),
+			result.Count(tg.ActionOverwritten), result.FilesPatched, result.Count(tg.ActionSkipped))
+	case applyArchive != "":
+		SuccessColor.Printf("✓ Template written to %s\n", BoldColor.Sprint(applyArchive))
+	default:
+		SuccessColor.Println("✓ Template applied successfully!")
+	}
+	PrintVerbose("  Output: %s\n", BoldColor.Sprint(destination))
 	PrintVerbose("  Processed files: %d\n", result.FilesCreated)
 	PrintVerbose("  Created directories: %d\n", result.DirsCreated)
-	PrintVerbose("  Created files: %d\n", len(result.CreatedFiles))
+	PrintVerbose("  Created files: %d\n", result.Count(tg.ActionCreated))
+	PrintVerbose("  Overwritten files: %d\n", result.Count(tg.ActionOverwritten))
+	PrintVerbose("  Unchanged files: %d\n", result.Count(tg.ActionSkipped))
+	PrintVerbose("  Patched files: %d\n", result.FilesPatched)
 
-	for _, file := range result.CreatedFiles {
-		PrintVerbose("    %s\n", file)
+	for _, file := range result.Files {
+		path := file.Output
+		if path == "" {
+			path = file.Source
+		}
+		PrintVerbose("    %-11s %s\n", file.Action, path)
 	}
 
 	return nil
 }
 
-func resolveTemplateDir(cfg *config.Config, requestedName string) (string, *config.Template, error) {
-	candidateDir := filepath.Join(cfg.TemplatesDir, requestedName)
-	if info, err := os.Stat(candidateDir); err == nil && info.IsDir() {
-		if _, err := os.Stat(filepath.Join(candidateDir, config.TemplateConfigFile)); err == nil {
-			tmpl, err := config.LoadTemplate(candidateDir)
-			if err != nil {
-				return "", nil, fmt.Errorf("failed to load template: %w", err)
+type applyReportSummary struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Copied      int `json:"copied"`
+	Ignored     int `json:"ignored"`
+	Injected    int `json:"injected"`
+	Appended    int `json:"appended"`
+	Prepended   int `json:"prepended"`
+	Replaced    int `json:"replaced"`
+}
+
+type applyReportOutput struct {
+	Template  templateOutput     `json:"template"`
+	OutputDir string             `json:"output_dir"`
+	Summary   applyReportSummary `json:"summary"`
+	Files     []tg.FileResult    `json:"files"`
+}
+
+func writeApplyReport(w io.Writer, format, templateDir string, tmpl *tg.Config, outputDir string, result *tg.Result) error {
+	report := applyReportOutput{
+		Template:  newTemplateOutput(templateDir, tmpl),
+		OutputDir: outputDir,
+		Summary:   newApplyReportSummary(result),
+		Files:     result.Files,
+	}
+
+	return encodeOutput(w, format, report)
+}
+
+func newApplyReportSummary(result *tg.Result) applyReportSummary {
+	return applyReportSummary{
+		Created:     result.Count(tg.ActionCreated),
+		Overwritten: result.Count(tg.ActionOverwritten),
+		Skipped:     result.Count(tg.ActionSkipped),
+		Copied:      result.Count(tg.ActionCopied),
+		Ignored:     result.Count(tg.ActionIgnored),
+		Injected:    result.Count(tg.ActionInjected),
+		Appended:    result.Count(tg.ActionAppended),
+		Prepended:   result.Count(tg.ActionPrepended),
+		Replaced:    result.Count(tg.ActionReplaced),
+	}
+}
+
+// recordManifest stores the generated files and their hashes in the
+// output directory's manifest, replacing any earlier entry for the template.
+// The generator runs of the earlier entry are kept, with the hashes of the
+// files they created that this run did not write.
+func recordManifest(outputDir string, tmpl *tg.Template, variables map[string]any, result *tg.Result) error {
+	m, err := manifest.Load(outputDir)
+	if err != nil {
+		return err
+	}
+
+	entry := manifest.Entry{
+		Name:        tmpl.Name(),
+		Version:     tmpl.Config.Version,
+		TGVersion:   Version,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Variables:   config.WithoutSecrets(tmpl.Config.Variables, variables),
+		Files:       make(map[string]string),
+	}
+	if previous, ok := m.Get(entry.Name); ok {
+		entry.Generators = previous.Generators
+		for _, run := range previous.Generators {
+			for _, name := range run.Files {
+				if hash, ok := previous.Files[name]; ok {
+					entry.Files[name] = hash
+				}
 			}
-			return candidateDir, tmpl, nil
 		}
 	}
+	recordFiles(&entry, result)
 
-	entries, err := os.ReadDir(cfg.TemplatesDir)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to read templates directory: %w", err)
+	m.Set(entry)
+	if err := m.Save(outputDir); err != nil {
+		return err
 	}
+	PrintVerbose("Recorded %d file(s) in %s\n", len(entry.Files), filepath.Join(outputDir, manifest.FileName))
+	return nil
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+// recordFiles stores the hash of every file result wrote or patched in
+// entry. Files only patched that entry did not hold yet are marked as
+// patched. It returns the files result created.
+func recordFiles(entry *manifest.Entry, result *tg.Result) []string {
+	known := maps.Clone(entry.Files)
+	var created []string
+
+	// Patches come after the files they change, so their hash wins
+	for _, file := range result.Files {
+		if file.Action == tg.ActionIgnored || file.Output == "" || file.Hash == "" {
 			continue
 		}
-		dir := filepath.Join(cfg.TemplatesDir, entry.Name())
-		if _, err := os.Stat(filepath.Join(dir, config.TemplateConfigFile)); err != nil {
+		name := filepath.ToSlash(file.Output)
+		entry.Files[name] = file.Hash
+
+		if !file.Patch {
+			created = append(created, name)
+			entry.Patched = slices.DeleteFunc(entry.Patched, func(patched string) bool { return patched == name })
 			continue
 		}
-		tmpl, err := config.LoadTemplate(dir)
+		if _, ok := known[name]; !ok && !slices.Contains(created, name) && !slices.Contains(entry.Patched, name) {
+			entry.Patched = append(entry.Patched, name)
+		}
+	}
+
+	sort.Strings(entry.Patched)
+	sort.Strings(created)
+	return created
+}
+
+// applyToTarget renders into the sink chosen by --archive, --stdout or
+// --dry-run, or into the output directory. It returns where the files went.
+func applyToTarget(tmpl *tg.Template, variables map[string]any) (*tg.Result, string, error) {
+	switch {
+	case applyArchive != "":
+		format, err := tg.ArchiveFormatFor(applyArchive)
+		if err != nil {
+			return nil, "", err
+		}
+
+		file, err := os.Create(applyArchive)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to load template: %w", err)
+			return nil, "", fmt.Errorf("failed to create archive: %w", err)
 		}
-		if tmpl.Metadata.Name == requestedName {
-			return dir, tmpl, nil
+
+		result, err := tmpl.ApplyTo(tg.NewArchiveSink(file, format), variables)
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write archive: %w", closeErr)
 		}
-	}
+		if err != nil {
+			os.Remove(applyArchive)
+			return nil, "", err
+		}
+		return result, applyArchive, nil
+
+	case applyStdout != "":
+		name := applyStdout
+		if name == streamOnlyFile {
+			name = ""
+		}
+		result, err := tmpl.ApplyTo(tg.NewStreamSink(os.Stdout, name), variables)
+		return result, "stdout", err
+
+	case applyDryRun:
+		result, err := tmpl.ApplyTo(tg.NewMemorySink(os.DirFS(applyOutputPath)), variables)
+		return result, applyOutputPath, err
 
-	return "", nil, fmt.Errorf("template '%s' not found in '%s'", requestedName, cfg.TemplatesDir)
+	default:
+		result, err := tmpl.Apply(applyOutputPath, variables)
+		return result, applyOutputPath, err
+	}
 }
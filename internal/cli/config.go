@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configGlobal     bool
+	configProject    bool
+	configShowOrigin bool
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read and edit tg configuration",
+		Long: `Config reads and edits settings in the project config (tg.config.toml, found
+in the current directory or any parent) and the user config
+($XDG_CONFIG_HOME/tg/config.toml). Project settings take precedence.
+
+Known keys: templates_dir, template_paths, registry and
+defaults.<variable>.`,
+		Example: `  # Show the effective configuration and where each value comes from
+  tg config list --show-origin
+
+  # Set a default author for every template, for this user only
+  tg config set defaults.author "Jane Doe" --global
+
+  # Search a shared template directory in this project
+  tg config set template_paths '[".tg", "~/work/templates"]'`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configuration values",
+		Args:  cobra.NoArgs,
+		RunE:  runConfigList,
+	}
+	listCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "Show the file each value comes from")
+
+	getCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a configuration value",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigGet,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a configuration value",
+		Long: `Set writes a value to the project config, or to the user config with --global.
+
+Values are read as TOML when possible, so true, 8080 and ["a", "b"] keep their
+types; anything else is stored as a string.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runConfigSet,
+	}
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a configuration value",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigUnset,
+	}
+
+	for _, sub := range []*cobra.Command{listCmd, getCmd, setCmd, unsetCmd} {
+		sub.Flags().BoolVarP(&configGlobal, "global", "g", false, "Use the user config file")
+	}
+	for _, sub := range []*cobra.Command{listCmd, getCmd} {
+		sub.Flags().BoolVarP(&configProject, "project", "p", false, "Use the project config file only")
+	}
+
+	cmd.AddCommand(listCmd, getCmd, setCmd, unsetCmd)
+	return cmd
+}
+
+type configValueOutput struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Origin string `json:"origin"`
+}
+
+type configListOutput struct {
+	Values []configValueOutput `json:"values"`
+}
+
+// configLayers returns the config files to read, lowest precedence first.
+func configLayers() ([]string, error) {
+	if configGlobal && configProject {
+		return nil, fmt.Errorf("--global and --project cannot be used together")
+	}
+
+	userPath := config.UserConfigFile()
+	projectPath, err := projectConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case configGlobal:
+		return []string{userPath}, nil
+	case configProject:
+		return []string{projectPath}, nil
+	default:
+		return []string{userPath, projectPath}, nil
+	}
+}
+
+// projectConfigPath returns the project config to edit: the --config file,
+// the nearest tg.config.toml, or a new one in the current directory.
+func projectConfigPath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+
+	found, err := config.FindConfig(".")
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return config.DefaultConfigFile, nil
+	}
+	return found, nil
+}
+
+func editConfigPath() (string, error) {
+	if configGlobal {
+		path := config.UserConfigFile()
+		if path == "" {
+			return "", fmt.Errorf("cannot determine the user config directory")
+		}
+		return path, nil
+	}
+	return projectConfigPath()
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	layers, err := configLayers()
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]any)
+	origins := make(map[string]string)
+	for _, path := range layers {
+		layer, err := config.ListValues(path)
+		if err != nil {
+			return err
+		}
+		for key, value := range layer {
+			values[key] = value
+			origins[key] = path
+		}
+	}
+
+	output := configListOutput{Values: make([]configValueOutput, 0, len(values))}
+	for _, key := range config.SortedKeys(values) {
+		output.Values = append(output.Values, configValueOutput{
+			Key:    key,
+			Value:  values[key],
+			Origin: origins[key],
+		})
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	for _, value := range output.Values {
+		if configShowOrigin {
+			fmt.Printf("%s\t", value.Origin)
+		}
+		fmt.Printf("%s = %s\n", value.Key, formatConfigValue(value.Value))
+	}
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := config.ValidateKey(key); err != nil {
+		return err
+	}
+
+	layers, err := configLayers()
+	if err != nil {
+		return err
+	}
+
+	var (
+		value  any
+		origin string
+		found  bool
+	)
+	for i := len(layers) - 1; i >= 0 && !found; i-- {
+		value, found, err = config.GetValue(layers[i], key)
+		if err != nil {
+			return err
+		}
+		origin = layers[i]
+	}
+
+	if !found {
+		if key != "templates_dir" || configGlobal {
+			return fmt.Errorf("key '%s' is not set", key)
+		}
+		value, origin = config.DefaultTemplateDir, "default"
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(configValueOutput{Key: key, Value: value, Origin: origin})
+	}
+
+	// Print strings bare so the value can be used in scripts
+	if text, ok := value.(string); ok {
+		fmt.Println(text)
+		return nil
+	}
+	fmt.Println(formatConfigValue(value))
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := config.ValidateKey(key); err != nil {
+		return err
+	}
+
+	path, err := editConfigPath()
+	if err != nil {
+		return err
+	}
+
+	value := config.ParseValue(args[1])
+	if err := config.SetValue(path, key, value); err != nil {
+		return err
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(configValueOutput{Key: key, Value: value, Origin: path})
+	}
+
+	SuccessColor.Printf("✓ Set %s = %s", key, formatConfigValue(value))
+	fmt.Printf(" in %s\n", path)
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := config.ValidateKey(key); err != nil {
+		return err
+	}
+
+	path, err := editConfigPath()
+	if err != nil {
+		return err
+	}
+
+	removed, err := config.UnsetValue(path, key)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("key '%s' is not set in %s", key, path)
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(configValueOutput{Key: key, Origin: path})
+	}
+
+	SuccessColor.Printf("✓ Removed %s", key)
+	fmt.Printf(" from %s\n", path)
+	return nil
+}
+
+func formatConfigValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		text := "["
+		for i, item := range v {
+			if i > 0 {
+				text += ", "
+			}
+			text += formatConfigValue(item)
+		}
+		return text + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
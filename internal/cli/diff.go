@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/diff"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffVariables map[string]string
+	diffVarFiles  map[string]string
+	diffVarEnvs   map[string]string
+	diffExitCode  bool
+)
+
+// File statuses reported by tg diff
+const (
+	diffAdded    = "added"
+	diffChanged  = "changed"
+	diffOrphaned = "orphaned"
+)
+
+func newDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <template-name> [dir]",
+		Short: "Show how a directory differs from a template's output",
+		Long: `Diff renders a template in memory and compares the result with the files
+currently in a directory, which defaults to the current directory. Nothing
+is written.
+
+Files the template would create are listed as added, files whose content
+differs as changed (with a unified diff), and files in the directory that
+the template does not generate as orphaned. Variables are resolved the same
+way as for apply.`,
+		Example: `  # Audit a service against the canonical template
+  tg diff web-app ./services/api -v project_name=api
+
+  # Fail in CI when the service has drifted
+  tg diff web-app ./services/api --exit-code`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runDiff,
+	}
+
+	cmd.Flags().StringToStringVarP(&diffVariables, "var", "v", nil, "Set variable values (e.g. -v name=John -v age=30)")
+	cmd.Flags().StringToStringVar(&diffVarFiles, "var-file", nil, "Read a variable's value from a file")
+	cmd.Flags().StringToStringVar(&diffVarEnvs, "var-env", nil, "Read a variable's value from an environment variable")
+	cmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with a non-zero status when there are differences")
+
+	return cmd
+}
+
+type diffSummary struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Orphaned  int `json:"orphaned"`
+	Unchanged int `json:"unchanged"`
+}
+
+type diffOutput struct {
+	Template templateOutput   `json:"template"`
+	Dir      string           `json:"dir"`
+	Summary  diffSummary      `json:"summary"`
+	Files    []fileDiffOutput `json:"files"`
+}
+
+type fileDiffOutput struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	templateName := args[0]
+	dir := "."
+	if len(args) > 1 {
+		dir = args[1]
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	chain, err := resolveTemplateChain(cfg, templateName)
+	if err != nil {
+		return err
+	}
+
+	overrides, err := variableOverrides(diffVariables, diffVarFiles, diffVarEnvs)
+	if err != nil {
+		return err
+	}
+
+	variables, err := chain.Template.Resolve(cfg.Defaults, overrides)
+	if err != nil {
+		return err
+	}
+
+	onDisk := os.DirFS(dir)
+	sink := tg.NewMemorySink(onDisk)
+	result, err := chain.Template.ApplyTo(sink, variables)
+	if err != nil {
+		return fmt.Errorf("failed to process template: %w", err)
+	}
+
+	existing, err := readWorkingTree(onDisk)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	output := diffOutput{
+		Template: newTemplateOutput(chain.Dir(), chain.Template.Config),
+		Dir:      dir,
+		Files:    []fileDiffOutput{},
+	}
+	// Unchanged files are not written to the sink, so the result is what
+	// tells which files the template generates
+	generated := make(map[string]bool)
+	for _, file := range result.Files {
+		if file.Action != tg.ActionIgnored && file.Output != "" {
+			generated[filepath.ToSlash(file.Output)] = true
+		}
+	}
+
+	for name, content := range sink.Files() {
+		name = filepath.ToSlash(name)
+
+		current, ok := existing[name]
+		switch {
+		case !ok:
+			output.Files = append(output.Files, fileDiffOutput{
+				Path:   name,
+				Status: diffAdded,
+				Diff:   contentDiff("/dev/null", path.Join("b", name), nil, content),
+			})
+		case !bytes.Equal(current, content):
+			output.Files = append(output.Files, fileDiffOutput{
+				Path:   name,
+				Status: diffChanged,
+				Diff:   contentDiff(path.Join("a", name), path.Join("b", name), current, content),
+			})
+		}
+	}
+
+	for name := range existing {
+		if !generated[name] {
+			output.Files = append(output.Files, fileDiffOutput{Path: name, Status: diffOrphaned})
+		}
+	}
+
+	sort.Slice(output.Files, func(i, j int) bool {
+		return output.Files[i].Path < output.Files[j].Path
+	})
+	for _, file := range output.Files {
+		switch file.Status {
+		case diffAdded:
+			output.Summary.Added++
+		case diffChanged:
+			output.Summary.Changed++
+		case diffOrphaned:
+			output.Summary.Orphaned++
+		}
+	}
+	output.Summary.Unchanged = len(generated) - output.Summary.Added - output.Summary.Changed
+
+	if IsMachineOutput() {
+		if err := writeOutput(output); err != nil {
+			return err
+		}
+	} else {
+		displayDiffResult(templateName, output)
+	}
+
+	if diffExitCode && len(output.Files) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("'%s' differs from template '%s'", dir, chain.Template.Name())
+	}
+	return nil
+}
+
+// readWorkingTree reads every file under fsys except version control data
+// and tg's own manifest.
+func readWorkingTree(fsys fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || name == manifest.FileName {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = content
+		return nil
+	})
+	return files, err
+}
+
+func displayDiffResult(templateName string, output diffOutput) {
+	InfoColor.Printf("Comparing %s with %s\n", BoldColor.Sprint(templateName), BoldColor.Sprint(output.Dir))
+
+	if len(output.Files) == 0 {
+		SuccessColor.Println("✓ No differences")
+		return
+	}
+
+	fmt.Println()
+	for _, file := range output.Files {
+		switch file.Status {
+		case diffAdded:
+			SuccessColor.Printf("  %-9s", file.Status)
+		case diffChanged:
+			WarnColor.Printf("  %-9s", file.Status)
+		default:
+			ErrorColor.Printf("  %-9s", file.Status)
+		}
+		fmt.Printf(" %s\n", file.Path)
+	}
+
+	for _, file := range output.Files {
+		if file.Diff != "" {
+			fmt.Println()
+			printDiff(file.Diff, "")
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d added, %d changed, %d orphaned, %d unchanged\n",
+		output.Summary.Added, output.Summary.Changed, output.Summary.Orphaned, output.Summary.Unchanged)
+}
+
+func contentDiff(oldName, newName string, before, after []byte) string {
+	if isBinaryContent(before) || isBinaryContent(after) {
+		return "Binary files differ\n"
+	}
+	return diff.Unified(oldName, newName, string(before), string(after))
+}
+
+func isBinaryContent(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// printDiff prints a unified diff with added and removed lines colored.
+func printDiff(text, indent string) {
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			BoldColor.Print(indent + line)
+		case strings.HasPrefix(line, "+"):
+			SuccessColor.Print(indent + line)
+		case strings.HasPrefix(line, "-"):
+			ErrorColor.Print(indent + line)
+		case strings.HasPrefix(line, "@@"):
+			InfoColor.Print(indent + line)
+		default:
+			fmt.Print(indent + line)
+		}
+	}
+}
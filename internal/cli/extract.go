@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/extract"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractName      string
+	extractVariables map[string]string
+	extractExcludes  []string
+	extractForce     bool
+)
+
+func newExtractCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extract <dir>",
+		Short: "Create a template from an existing project",
+		Long: `Extract copies a project into a new template in the templates directory.
+
+Every occurrence of a --var value in file contents and paths is replaced by
+the expression that renders it, including case variants: for
+project_name=payments-api, PaymentsApi becomes {{pascal .project_name}},
+payments_api {{snake .project_name}}, PAYMENTS_API
+{{upper (snake .project_name)}} and so on. Existing "{{" are escaped.
+
+The variables are written to template.toml with the project's values as
+defaults, and the template is rendered back in memory to check that it
+reproduces the project. Binary files are copied unchanged; .git is left
+out.`,
+		Example: `  # Turn a service into a template
+  tg extract ./services/payments --name svc -v project_name=payments -v org=acme
+
+  # Leave out build output
+  tg extract ./web --name web-app -v project_name=shop -x node_modules -x dist`,
+		Args: cobra.ExactArgs(1),
+		RunE: runExtract,
+	}
+
+	cmd.Flags().StringVarP(&extractName, "name", "n", "", "Name of the new template (required)")
+	cmd.Flags().StringToStringVarP(&extractVariables, "var", "v", nil, "Variable and the literal value it replaces (e.g. -v project_name=payments)")
+	cmd.Flags().StringSliceVarP(&extractExcludes, "exclude", "x", nil, "Leave out files and directories matching a glob pattern")
+	cmd.Flags().BoolVarP(&extractForce, "force", "f", false, "Replace an existing template with the same name")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+type extractOutput struct {
+	Name         string                    `json:"name"`
+	Path         string                    `json:"path"`
+	Source       string                    `json:"source"`
+	Files        int                       `json:"files"`
+	Replacements []extractReplacementCount `json:"replacements"`
+	// Mismatches lists files the template does not render back exactly
+	Mismatches []string `json:"mismatches"`
+}
+
+type extractReplacementCount struct {
+	extract.Replacement
+	Count int `json:"count"`
+}
+
+func runExtract(cmd *cobra.Command, args []string) error {
+	sourceDir := args[0]
+
+	info, err := os.Stat(sourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", sourceDir)
+	}
+
+	if len(extractVariables) == 0 {
+		return fmt.Errorf("at least one --var is required")
+	}
+	for name, value := range extractVariables {
+		if !isIdentifier(name) {
+			return fmt.Errorf("invalid variable name '%s'", name)
+		}
+		if len(value) < 3 {
+			WarnColor.Fprintf(os.Stderr, "Warning: '%s' is short and may match unrelated text\n", value)
+		}
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.TemplatesDir == "" {
+		return fmt.Errorf("no templates directory configured (run 'tg init' first)")
+	}
+
+	if err := config.ValidateTemplateName(extractName); err != nil {
+		return err
+	}
+	templateDir := filepath.Join(cfg.TemplatesDir, extractName)
+	_, err = os.Stat(templateDir)
+	exists := err == nil
+	if exists && !extractForce {
+		return fmt.Errorf("template directory '%s' already exists. Use --force to replace it", templateDir)
+	}
+
+	files, err := readProject(sourceDir, cfg.TemplatesDir)
+	if err != nil {
+		return err
+	}
+	if _, ok := files[config.TemplateConfigFile]; ok {
+		return fmt.Errorf("'%s' already contains a %s", sourceDir, config.TemplateConfigFile)
+	}
+
+	replacer, err := extract.NewReplacer(extractVariables)
+	if err != nil {
+		return err
+	}
+
+	// The template is written and checked next to the others, then moved
+	// into place, so a failure leaves an existing template untouched
+	if err := os.MkdirAll(cfg.TemplatesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(cfg.TemplatesDir, ".tg-extract-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	extracted := filepath.Join(staging, "template")
+
+	counts := make(map[string]int)
+	for name, content := range files {
+		target := filepath.Join(extracted, filepath.FromSlash(replacer.ReplacePath(name, counts)))
+		if !isBinaryContent(content) {
+			content = []byte(replacer.Replace(string(content), counts))
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		PrintVerbose("Extracted %s\n", name)
+	}
+
+	if err := writeExtractedConfig(extracted, sourceDir); err != nil {
+		return err
+	}
+
+	mismatches, err := verifyExtracted(extracted, files)
+	if err != nil {
+		return fmt.Errorf("extracted template does not render: %w", err)
+	}
+
+	previous := filepath.Join(staging, "previous")
+	if exists {
+		if err := os.Rename(templateDir, previous); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", templateDir, err)
+		}
+	}
+	if err := os.Rename(extracted, templateDir); err != nil {
+		if exists {
+			os.Rename(previous, templateDir)
+		}
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+
+	output := extractOutput{
+		Name:         extractName,
+		Path:         templateDir,
+		Source:       sourceDir,
+		Files:        len(files),
+		Replacements: []extractReplacementCount{},
+		Mismatches:   mismatches,
+	}
+	for _, replacement := range replacer.Replacements() {
+		if counts[replacement.Text] > 0 {
+			output.Replacements = append(output.Replacements, extractReplacementCount{replacement, counts[replacement.Text]})
+		}
+	}
+	sort.SliceStable(output.Replacements, func(i, j int) bool {
+		return output.Replacements[i].Variable < output.Replacements[j].Variable
+	})
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+	displayExtractResult(output)
+	return nil
+}
+
+// readProject reads every regular file under dir, keyed by slash-separated
+// path, leaving out .git, tg's manifest, --exclude matches and skip (the
+// templates directory, if it is inside dir).
+func readProject(dir, skip string) (map[string][]byte, error) {
+	skipAbs, _ := filepath.Abs(skip)
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(dir, current)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relative)
+		if name == "." {
+			return nil
+		}
+
+		abs, _ := filepath.Abs(current)
+		excluded := d.Name() == ".git" || name == manifest.FileName || abs == skipAbs || matchesAny(extractExcludes, name)
+		switch {
+		case excluded && d.IsDir():
+			return fs.SkipDir
+		case excluded, d.IsDir():
+			return nil
+		case !d.Type().IsRegular():
+			PrintVerbose("Skipping %s: not a regular file\n", name)
+			return nil
+		}
+
+		content, err := os.ReadFile(current)
+		if err != nil {
+			return err
+		}
+		files[name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+	return files, nil
+}
+
+// matchesAny reports whether a pattern matches the path or its base name.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(name)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func writeExtractedConfig(templateDir, sourceDir string) error {
+	names := make([]string, 0, len(extractVariables))
+	for name := range extractVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "version = \"0.1.0\"\n\n[metadata]\nname = %s\ndescription = %s\n",
+		strconv.Quote(extractName), strconv.Quote("Extracted from "+filepath.Base(filepath.Clean(sourceDir))))
+	for _, name := range names {
+		fmt.Fprintf(&content, "\n[variables.%s]\ntype = \"string\"\ndefault = %s\n", name, strconv.Quote(extractVariables[name]))
+	}
+
+	target := filepath.Join(templateDir, config.TemplateConfigFile)
+	if err := os.WriteFile(target, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
+
+// verifyExtracted renders the new template with its defaults in memory and
+// lists the files that differ from the project.
+func verifyExtracted(templateDir string, original map[string][]byte) ([]string, error) {
+	tmpl, err := tg.LoadDir(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	values, err := tmpl.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	sink := tg.NewMemorySink(nil)
+	if _, err := tmpl.ApplyTo(sink, values); err != nil {
+		return nil, err
+	}
+	rendered := make(map[string][]byte)
+	for name, content := range sink.Files() {
+		rendered[filepath.ToSlash(name)] = content
+	}
+
+	mismatches := []string{}
+	for name, content := range original {
+		if !bytes.Equal(rendered[name], content) {
+			mismatches = append(mismatches, name)
+		}
+	}
+	for name := range rendered {
+		if _, ok := original[name]; !ok {
+			mismatches = append(mismatches, name)
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches, nil
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		isLetter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func displayExtractResult(output extractOutput) {
+	SuccessColor.Printf("✓ Extracted %s ", BoldColor.Sprint(output.Name))
+	fmt.Printf("from %s (%d files)\n", output.Source, output.Files)
+	fmt.Printf("  Template: %s\n", output.Path)
+
+	if len(output.Replacements) == 0 {
+		WarnColor.Println("  No values were found; the template has no variables in use")
+	}
+	for _, replacement := range output.Replacements {
+		fmt.Printf("  %-24s -> %-32s %d\n", replacement.Text, replacement.Expression, replacement.Count)
+	}
+
+	if len(output.Mismatches) > 0 {
+		fmt.Println()
+		WarnColor.Println("! These files do not render back exactly; review them:")
+		for _, name := range output.Mismatches {
+			fmt.Printf("    %s\n", name)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	genOutputPath string
+	genVariables  map[string]string
+	genVarFiles   map[string]string
+	genVarEnvs    map[string]string
+	genDryRun     bool
+	genStrict     bool
+)
+
+func newGenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gen [template:]<generator> [output-dir] [--<variable> <value>...]",
+		Aliases: []string{"generate"},
+		Short:   "Run a generator to add files to an existing project",
+		Long: `Gen runs one of a template's named generators against an existing project,
+adding its files and injecting snippets into files that are already there.
+
+Generator variables can be passed as flags: --name User sets the variable
+"name" (dashes may stand in for underscores). Without arguments, gen lists
+every available generator. Use template:generator when several templates
+define a generator with the same name.`,
+		Example: `  # Add internal/handlers/user.go and register its route
+  tg gen handler --name User
+
+  # Pick the generator of a specific template
+  tg gen web-app:handler --name User ./services/api
+
+  # Show what would change
+  tg gen handler --name User --dry-run`,
+		DisableFlagParsing: true,
+		RunE:               runGen,
+	}
+
+	cmd.Flags().StringVarP(&genOutputPath, "output-dir", "o", ".", "Project directory")
+	cmd.Flags().StringToStringVarP(&genVariables, "var", "v", nil, "Set variable values (e.g. -v name=User)")
+	cmd.Flags().StringToStringVar(&genVarFiles, "var-file", nil, "Read a variable's value from a file")
+	cmd.Flags().StringToStringVar(&genVarEnvs, "var-env", nil, "Read a variable's value from an environment variable")
+	cmd.Flags().BoolVar(&genDryRun, "dry-run", false, "Report what would change without writing anything")
+	cmd.Flags().BoolVar(&genStrict, "strict", false, "Fail on references to undefined variables instead of rendering <no value>")
+	cmd.Flags().BoolP("help", "h", false, "help for gen")
+
+	return cmd
+}
+
+type generatorOutput struct {
+	Template    string           `json:"template,omitempty"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Variables   []variableOutput `json:"variables"`
+}
+
+type generatorListOutput struct {
+	Generators []generatorOutput `json:"generators"`
+}
+
+func runGen(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	flags.AddFlagSet(cmd.Root().PersistentFlags())
+
+	known, named := splitGeneratorArgs(flags, args)
+	if err := flags.Parse(known); err != nil {
+		return err
+	}
+	if help, _ := flags.GetBool("help"); help {
+		return cmd.Help()
+	}
+	if err := cmd.Root().PersistentPreRunE(cmd, args); err != nil {
+		return err
+	}
+
+	positional := flags.Args()
+	if len(positional) > 2 {
+		return fmt.Errorf("too many arguments: %s", strings.Join(positional[2:], " "))
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(positional) == 0 {
+		return listGenerators(cfg)
+	}
+	if len(positional) > 1 {
+		genOutputPath = positional[1]
+	}
+
+	templateName, generatorName, err := resolveGenerator(cfg, positional[0])
+	if err != nil {
+		return err
+	}
+
+	chain, err := resolveTemplateChain(cfg, templateName)
+	if err != nil {
+		return err
+	}
+
+	generator, err := chain.Template.Generator(generatorName)
+	if err != nil {
+		return err
+	}
+
+	overrides, err := variableOverrides(genVariables, genVarFiles, genVarEnvs)
+	if err != nil {
+		return err
+	}
+	for key, value := range named {
+		name, err := generatorVariable(generator.Config, key)
+		if err != nil {
+			return err
+		}
+		overrides[name] = value
+	}
+
+	variables, err := generator.Resolve(cfg.Defaults, overrides)
+	if err != nil {
+		return err
+	}
+	printVariables(generator.Config.Variables, variables)
+
+	if genStrict {
+		generator.SetStrict(true)
+	}
+
+	var result *tg.Result
+	if genDryRun {
+		result, err = generator.ApplyTo(tg.NewMemorySink(os.DirFS(genOutputPath)), variables)
+	} else {
+		result, err = generator.Apply(genOutputPath, variables)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run generator %s: %w", generator.Name(), err)
+	}
+	if !genDryRun {
+		run := manifest.GeneratorRun{
+			Name:      generatorName,
+			Variables: config.WithoutSecrets(generator.Config.Variables, variables),
+		}
+		if err := recordGeneratorRun(genOutputPath, chain.Template.Name(), run, result); err != nil {
+			return err
+		}
+	}
+
+	if IsMachineOutput() {
+		return writeApplyReport(os.Stdout, outputFormat, chain.Dir(), generator.Config, genOutputPath, result)
+	}
+
+	if genDryRun {
+		SuccessColor.Printf("✓ Dry run of %s, nothing was written\n", generator.Name())
+	} else {
+		SuccessColor.Printf("✓ Generated %s\n", generator.Name())
+	}
+	for _, file := range result.Files {
+		if file.Action == tg.ActionIgnored || file.Output == "" {
+			continue
+		}
+		fmt.Printf("  %-11s %s\n", file.Action, file.Output)
+	}
+	return nil
+}
+
+// recordGeneratorRun adds run, with the files it created, to the manifest
+// entry of the template in dir and stores the hashes of every file it wrote
+// or patched. A project the template was not applied to has no entry, and
+// nothing is recorded.
+func recordGeneratorRun(dir, templateName string, run manifest.GeneratorRun, result *tg.Result) error {
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	entry, ok := m.Get(templateName)
+	if !ok {
+		PrintVerbose("%s has no %s entry for %s, the generator run is not recorded\n", dir, manifest.FileName, templateName)
+		return nil
+	}
+
+	if entry.Files == nil {
+		entry.Files = make(map[string]string)
+	}
+	run.Files = recordFiles(&entry, result)
+	entry.Generators = append(entry.Generators, run)
+
+	m.Set(entry)
+	return m.Save(dir)
+}
+
+// splitGeneratorArgs separates the command's own flags from --<variable>
+// flags. A variable flag without a value is "true".
+func splitGeneratorArgs(flags *pflag.FlagSet, args []string) ([]string, map[string]string) {
+	var known []string
+	named := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(known, args[i:]...), named
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			if flag := flags.Lookup(name); flag != nil {
+				known = append(known, arg)
+				if !hasValue && flag.NoOptDefVal == "" && flag.Value.Type() != "bool" && i+1 < len(args) {
+					i++
+					known = append(known, args[i])
+				}
+				continue
+			}
+
+			if !hasValue {
+				value = "true"
+				if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					value = args[i]
+				}
+			}
+			named[name] = value
+
+		case strings.HasPrefix(arg, "-") && len(arg) == 2:
+			known = append(known, arg)
+			if flag := flags.ShorthandLookup(arg[1:]); flag != nil && flag.Value.Type() != "bool" && i+1 < len(args) {
+				i++
+				known = append(known, args[i])
+			}
+
+		default:
+			known = append(known, arg)
+		}
+	}
+
+	return known, named
+}
+
+// generatorVariable maps a flag name to a declared variable, accepting
+// dashes for underscores.
+func generatorVariable(tmpl *tg.Config, flag string) (string, error) {
+	if _, ok := tmpl.Variables[flag]; ok {
+		return flag, nil
+	}
+	if name := strings.ReplaceAll(flag, "-", "_"); name != flag {
+		if _, ok := tmpl.Variables[name]; ok {
+			return name, nil
+		}
+	}
+
+	names := make([]string, 0, len(tmpl.Variables))
+	for _, variable := range tmpl.OrderedVariables() {
+		names = append(names, variable.Name)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("unknown flag --%s: generator %s has no variables", flag, tmpl.Metadata.Name)
+	}
+	return "", fmt.Errorf("unknown flag --%s (variables of %s: %s)", flag, tmpl.Metadata.Name, strings.Join(names, ", "))
+}
+
+// resolveGenerator splits "template:generator", or finds the one template
+// that defines generator.
+func resolveGenerator(cfg *config.Config, reference string) (string, string, error) {
+	if templateName, generatorName, ok := strings.Cut(reference, ":"); ok {
+		return templateName, generatorName, nil
+	}
+
+	templates, err := discoverTemplates(cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	var owners []string
+	for _, discovered := range templates {
+		if _, ok := discovered.Template.Generators[reference]; ok {
+			owners = append(owners, discovered.Template.Metadata.Name)
+		}
+	}
+
+	switch len(owners) {
+	case 0:
+		return "", "", fmt.Errorf("no template defines a generator named '%s'. Run 'tg gen' to list generators", reference)
+	case 1:
+		return owners[0], reference, nil
+	default:
+		return "", "", fmt.Errorf("generator '%s' is defined by several templates (%s); use <template>:%s", reference, strings.Join(owners, ", "), reference)
+	}
+}
+
+func listGenerators(cfg *config.Config) error {
+	templates, err := discoverTemplates(cfg)
+	if err != nil {
+		return err
+	}
+
+	output := generatorListOutput{Generators: []generatorOutput{}}
+	for _, discovered := range templates {
+		tmpl := discovered.Template
+		for _, name := range tmpl.GeneratorNames() {
+			generator, err := tmpl.Generator(name)
+			if err != nil {
+				return err
+			}
+			output.Generators = append(output.Generators, generatorOutput{
+				Template:    tmpl.Metadata.Name,
+				Name:        name,
+				Description: generator.Metadata.Description,
+				Variables:   newVariablesOutput(generator.Variables),
+			})
+		}
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	if len(output.Generators) == 0 {
+		WarnColor.Println("No generators found")
+		fmt.Println("Declare one in template.toml under [generators.<name>]")
+		return nil
+	}
+
+	InfoColor.Printf("Found %d generator(s):\n\n", len(output.Generators))
+	for _, generator := range output.Generators {
+		fmt.Printf("  • %s:%s", generator.Template, BoldColor.Sprint(generator.Name))
+		if generator.Description != "" {
+			fmt.Printf(" - %s", generator.Description)
+		}
+		fmt.Println()
+		for _, variable := range generator.Variables {
+			fmt.Printf("      --%s", variable.Name)
+			if variable.Required {
+				fmt.Print(" (required)")
+			}
+			if variable.Description != "" {
+				fmt.Printf("  %s", variable.Description)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+)
+
+func TestRecordGeneratorRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "NOTES.md"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Template{
+		Generators: map[string]config.Generator{
+			"handler": {
+				Variables: map[string]config.Variable{"name": {Type: "string"}},
+				Actions: []config.Action{
+					{File: "router.go", InsertAfter: "// routes", Content: "route({{.name}})"},
+					{File: "NOTES.md", Append: "{{.name}}\n"},
+				},
+			},
+		},
+	}
+	cfg.Metadata.Name = "web"
+	tmpl := tg.New(cfg, fstest.MapFS{
+		"router.go":                       {Data: []byte("// routes\n")},
+		"generators/handler/{{.name}}.go": {Data: []byte("package {{.name}}\n")},
+	})
+
+	applied, err := tmpl.Apply(dir, map[string]any{})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if err := recordManifest(dir, tmpl, map[string]any{}, applied); err != nil {
+		t.Fatalf("recordManifest() error = %v", err)
+	}
+
+	generator, err := tmpl.Generator("handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := map[string]any{"name": "user"}
+	result, err := generator.Apply(dir, variables)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	run := manifest.GeneratorRun{Name: "handler", Variables: variables}
+	if err := recordGeneratorRun(dir, "web", run, result); err != nil {
+		t.Fatalf("recordGeneratorRun() error = %v", err)
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := m.Get("web")
+
+	states, err := entry.Check(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"router.go", "user.go", "NOTES.md"} {
+		if states[name] != manifest.FileUnchanged {
+			t.Errorf("%s is %q, want unchanged", name, states[name])
+		}
+	}
+
+	if len(entry.Generators) != 1 || !slices.Equal(entry.Generators[0].Files, []string{"user.go"}) {
+		t.Errorf("Generators = %+v, want one run that created user.go", entry.Generators)
+	}
+	if want := []string{"NOTES.md"}; !slices.Equal(entry.Patched, want) {
+		t.Errorf("Patched = %v, want %v", entry.Patched, want)
+	}
+	if generated := entry.Generated(); len(generated) != 1 || generated["router.go"] == "" {
+		t.Errorf("Generated() = %v, want only router.go", generated)
+	}
+
+	// Applying the template again keeps the run and the hash of its file
+	if err := recordManifest(dir, tmpl, map[string]any{}, applied); err != nil {
+		t.Fatalf("recordManifest() error = %v", err)
+	}
+	m, _ = manifest.Load(dir)
+	entry, _ = m.Get("web")
+	if len(entry.Generators) != 1 || entry.Files["user.go"] == "" {
+		t.Errorf("entry after apply = %+v, want the generator run kept", entry)
+	}
+}
@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+)
+
+func newInfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "info <template-name>",
+		Aliases: []string{"describe"},
+		Short:   "Show everything about a single template",
+		Long: `Info prints a template's metadata, variables with their constraints, rules,
+hooks, the templates it extends and the files it would generate.
+
+File names are resolved with the default variable values, honoring ignores,
+includes and renames.`,
+		Example: `  # Describe a template
+  tg info hello-world
+
+  # Machine-readable description
+  tg info hello-world --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: runInfo,
+	}
+
+	return cmd
+}
+
+type infoOutput struct {
+	Template templateOutput    `json:"template"`
+	Chain    []chainLinkOutput `json:"chain"`
+	Files    []infoFileOutput  `json:"files"`
+}
+
+type chainLinkOutput struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+type infoFileOutput struct {
+	Source string    `json:"source"`
+	Output string    `json:"output"`
+	Action tg.Action `json:"action"`
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	chain, err := resolveTemplateChain(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	tmpl := chain.Template.Config
+
+	if err := chain.Template.Parse(); err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	// Plan with the defaults as they are; required values may still be missing
+	variables := make(map[string]any)
+	for name, variable := range tmpl.Variables {
+		variables[name] = variable.Default
+	}
+
+	files, err := chain.Template.Plan(variables)
+	if err != nil {
+		return err
+	}
+
+	output := infoOutput{
+		Template: newTemplateOutput(chain.Dir(), tmpl),
+		Chain:    make([]chainLinkOutput, 0, len(chain.Dirs)),
+		Files:    make([]infoFileOutput, 0, len(files)),
+	}
+	for i, t := range chain.Template.Chain() {
+		output.Chain = append(output.Chain, chainLinkOutput{
+			Name:    t.Metadata.Name,
+			Version: t.Version,
+			Path:    chain.Dirs[i],
+		})
+	}
+	for _, file := range files {
+		output.Files = append(output.Files, infoFileOutput{
+			Source: filepath.ToSlash(file.Source),
+			Output: filepath.ToSlash(file.Output),
+			Action: file.Action,
+		})
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	displayInfo(output)
+	return nil
+}
+
+func displayInfo(info infoOutput) {
+	tmpl := info.Template
+
+	fmt.Print(BoldColor.Sprint(tmpl.Name))
+	if tmpl.Version != "" {
+		fmt.Printf(" (v%s)", tmpl.Version)
+	}
+	fmt.Println()
+	if tmpl.Description != "" {
+		fmt.Printf("  %s\n", tmpl.Description)
+	}
+	if tmpl.Author != "" {
+		fmt.Printf("  Author:  %s\n", tmpl.Author)
+	}
+	fmt.Printf("  Path:    %s\n", tmpl.Path)
+	if tmpl.MinTGVersion != "" {
+		fmt.Printf("  Needs:   tg >= %s\n", tmpl.MinTGVersion)
+	}
+	if len(info.Chain) > 1 {
+		names := make([]string, 0, len(info.Chain))
+		for _, link := range info.Chain {
+			names = append(names, link.Name)
+		}
+		fmt.Printf("  Extends: %s\n", strings.Join(names, " <- "))
+	}
+
+	fmt.Println()
+	InfoColor.Println("Variables:")
+	if len(tmpl.Variables) == 0 {
+		fmt.Println("  (none)")
+	}
+	group := ""
+	for _, variable := range tmpl.Variables {
+		if variable.Group != group {
+			group = variable.Group
+			fmt.Printf("  [%s]\n", group)
+		}
+		fmt.Printf("  %s (%s", BoldColor.Sprint(variable.Name), variable.Type)
+		if variable.Default != nil {
+			fmt.Printf(", default: %v", variable.Default)
+		}
+		if variable.Required {
+			fmt.Print(", required")
+		}
+		if variable.Secret {
+			fmt.Print(", secret")
+		}
+		fmt.Println(")")
+
+		if variable.Description != "" {
+			fmt.Printf("      %s\n", variable.Description)
+		}
+		if len(variable.Choices) > 0 {
+			fmt.Printf("      Choices: %v\n", variable.Choices)
+		}
+		if variable.Pattern != "" {
+			fmt.Printf("      Pattern: %s\n", variable.Pattern)
+		}
+		if variable.When != "" {
+			fmt.Printf("      When: %s\n", variable.When)
+		}
+	}
+
+	fmt.Println()
+	InfoColor.Println("Rules:")
+	fmt.Printf("  Ignores:  %s\n", listOrNone(tmpl.Rules.Ignores))
+	fmt.Printf("  Includes: %s\n", listOrNone(tmpl.Rules.Includes))
+	renames := make([]string, 0, len(tmpl.Rules.Renames))
+	for from, to := range tmpl.Rules.Renames {
+		renames = append(renames, fmt.Sprintf("%s -> %s", from, to))
+	}
+	sort.Strings(renames)
+	fmt.Printf("  Renames:  %s\n", listOrNone(renames))
+
+	if len(tmpl.Hooks.PreApply) > 0 || len(tmpl.Hooks.PostApply) > 0 {
+		fmt.Println()
+		InfoColor.Println("Hooks:")
+		for _, hook := range tmpl.Hooks.PreApply {
+			fmt.Printf("  pre_apply:  %s\n", hook)
+		}
+		for _, hook := range tmpl.Hooks.PostApply {
+			fmt.Printf("  post_apply: %s\n", hook)
+		}
+	}
+
+	if len(tmpl.Generators) > 0 {
+		fmt.Println()
+		InfoColor.Println("Generators:")
+		for _, generator := range tmpl.Generators {
+			fmt.Printf("  %s", BoldColor.Sprint(generator.Name))
+			if generator.Description != "" {
+				fmt.Printf(" - %s", generator.Description)
+			}
+			fmt.Println()
+			for _, variable := range generator.Variables {
+				fmt.Printf("      --%s\n", variable.Name)
+			}
+		}
+	}
+
+	if len(tmpl.Actions) > 0 {
+		fmt.Println()
+		InfoColor.Println("Actions:")
+		for _, action := range tmpl.Actions {
+			fmt.Printf("  %-13s %s", action.Op, action.File)
+			if action.Anchor != "" {
+				fmt.Printf(" (%s)", action.Anchor)
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Println()
+	InfoColor.Println("Files:")
+	var outputs []string
+	for _, file := range info.Files {
+		if file.Action != tg.ActionIgnored {
+			outputs = append(outputs, file.Output)
+		}
+	}
+	if len(outputs) == 0 {
+		fmt.Println("  (none)")
+	}
+	printFileTree(outputs)
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "(none)"
+	}
+	return strings.Join(values, ", ")
+}
+
+// printFileTree prints slash-separated paths as an indented tree
+func printFileTree(paths []string) {
+	sort.Strings(paths)
+
+	printed := make(map[string]bool)
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		for i := range segments {
+			prefix := strings.Join(segments[:i+1], "/")
+			if printed[prefix] {
+				continue
+			}
+			printed[prefix] = true
+
+			name := segments[i]
+			if i < len(segments)-1 {
+				name += "/"
+			}
+			fmt.Printf("  %s%s\n", strings.Repeat("  ", i), name)
+		}
+	}
+}
@@ -40,8 +40,17 @@ This command will:
 	return cmd
 }
 
+type initOutput struct {
+	ConfigFile   string `json:"config_file"`
+	TemplatesDir string `json:"templates_dir"`
+}
+
 func runInit(cmd *cobra.Command, args []string) error {
-	InfoColor.Println("Initializing tg configuration...")
+	if !IsMachineOutput() {
+		InfoColor.Println("Initializing tg configuration...")
+	}
+
+	configPath := GetConfigPath()
 
 	if !initForce {
 		if _, err := os.Stat(configPath); err == nil {
@@ -49,7 +58,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if err := createConfigFile(); err != nil {
+	if err := createConfigFile(configPath); err != nil {
 		return fmt.Errorf("failed to create config file: %w", err)
 	}
 	PrintVerbose("Created config file: %s\n", configPath)
@@ -59,6 +68,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 	PrintVerbose("Created templates directory: %s\n", initTemplatesDir)
 
+	if IsMachineOutput() {
+		return writeOutput(initOutput{ConfigFile: configPath, TemplatesDir: initTemplatesDir})
+	}
+
 	SuccessColor.Println("✓ Configuration initialized successfully!")
 	fmt.Printf("  Config file:    %s\n", BoldColor.Sprint(configPath))
 	fmt.Printf("  Templates dir:  %s\n", BoldColor.Sprint(initTemplatesDir))
@@ -66,11 +79,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func createConfigFile() error {
+func createConfigFile(configPath string) error {
 	content := fmt.Sprintf(`# Template Generator Configuration
 
 	# Directory containing templates
 	templates_dir = "%s"
+
+	# Ordered template search paths; the first template with a given name wins.
+	# ~/.config/tg/templates is always searched last. (optional)
+	# template_paths = [".tg", "~/work/shared-templates"]
 	
 	# Git remote for fetching templates (optional)
 	# git_remote = "https://github.com/yourusername/tg-templates.git"
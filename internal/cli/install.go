@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/bundle"
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	installName     string
+	installForce    bool
+	installGlobal   bool
+	installChecksum string
+)
+
+func newInstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install [bundle.tgz | url | name[@version]]",
+		Short: "Install a template bundle",
+		Long: `Install unpacks a bundle created by 'tg pack' into the templates directory.
+
+The bundle can be a local file, an http(s) URL, or a template name from the
+configured registry with an optional version constraint such as web-app@^1.2.
+Every file is checked against the bundle's manifest before anything is written,
+and --checksum additionally pins the checksum of the whole bundle.
+
+Project installs are recorded in tg.lock next to the project config. Running
+'tg install' without arguments installs exactly the locked versions.`,
+		Example: `  # Install the newest 1.x release from the registry
+  tg install web-app@^1.2
+
+  # Install everything recorded in tg.lock
+  tg install
+
+  # Install from a file
+  tg install ./web-app-1.2.0.tgz
+
+  # Install from a URL into the user-global templates directory
+  tg install https://example.com/templates/web-app-1.2.0.tgz --global
+
+  # Replace an installed template and pin the bundle checksum
+  tg install ./web-app-1.2.0.tgz --force --checksum sha256:9f86d0...`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runInstall,
+	}
+
+	cmd.Flags().StringVarP(&installName, "name", "n", "", "Install under a different directory name")
+	cmd.Flags().BoolVarP(&installForce, "force", "f", false, "Replace an existing template")
+	cmd.Flags().BoolVarP(&installGlobal, "global", "g", false, "Install into the user-global templates directory")
+	cmd.Flags().StringVar(&installChecksum, "checksum", "", "Expected sha256 of the bundle (sha256:<hex>)")
+
+	return cmd
+}
+
+type installOutput struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Files    int    `json:"files"`
+	Checksum string `json:"checksum"`
+	Source   string `json:"source"`
+}
+
+type installLockOutput struct {
+	Lockfile  string          `json:"lockfile"`
+	Installed []installOutput `json:"installed"`
+	UpToDate  []string        `json:"up_to_date"`
+}
+
+func runInstall(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	templatesDir, err := installTemplatesDir(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return installFromLock(cfg, templatesDir)
+	}
+
+	source := args[0]
+	checksum := installChecksum
+	force := installForce
+
+	if !isBundleSource(source) {
+		name, constraint := registry.ParseReference(source)
+		source, checksum, err = resolveRegistrySource(cfg, name, constraint)
+		if err != nil {
+			return err
+		}
+
+		// Moving between registry versions of a locked template is expected
+		lockName := name
+		if installName != "" {
+			lockName = installName
+		}
+		if !installGlobal && isLocked(cfg, lockName) {
+			force = true
+		}
+	}
+
+	output, err := installBundle(templatesDir, source, checksum, installName, force)
+	if err != nil {
+		return err
+	}
+
+	if !installGlobal {
+		if err := lockInstall(cfg, output); err != nil {
+			return err
+		}
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	printInstalled(output)
+	return nil
+}
+
+// isBundleSource reports whether source names a bundle directly rather than
+// a registry template.
+func isBundleSource(source string) bool {
+	if bundle.IsURL(source) || strings.HasSuffix(source, bundle.Extension) {
+		return true
+	}
+	_, err := os.Stat(source)
+	return err == nil
+}
+
+func resolveRegistrySource(cfg *config.Config, name, constraint string) (string, string, error) {
+	index, err := loadRegistry(cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	entry, ok := index.Find(name)
+	if !ok {
+		return "", "", fmt.Errorf("template '%s' not found in registry %s", name, cfg.Registry)
+	}
+
+	release, err := entry.Resolve(constraint)
+	if err != nil {
+		return "", "", err
+	}
+	PrintVerbose("Resolved %s@%s to %s\n", name, constraintOrLatest(constraint), release.Version)
+
+	source, err := index.DownloadURL(release)
+	if err != nil {
+		return "", "", err
+	}
+
+	checksum := release.Checksum
+	if installChecksum != "" {
+		if checksum != "" && checksum != installChecksum {
+			return "", "", fmt.Errorf("--checksum %s does not match the registry checksum %s", installChecksum, checksum)
+		}
+		checksum = installChecksum
+	}
+	return source, checksum, nil
+}
+
+func constraintOrLatest(constraint string) string {
+	if constraint == "" {
+		return "latest"
+	}
+	return constraint
+}
+
+func installBundle(templatesDir, source, expectedChecksum, name string, force bool) (installOutput, error) {
+	PrintVerbose("Fetching bundle: %s\n", source)
+	data, err := bundle.Fetch(source)
+	if err != nil {
+		return installOutput{}, err
+	}
+
+	checksum := bundle.Checksum(data)
+	if expectedChecksum != "" && expectedChecksum != checksum {
+		return installOutput{}, fmt.Errorf("bundle checksum mismatch: expected %s, got %s", expectedChecksum, checksum)
+	}
+
+	b, err := bundle.ReadBytes(data)
+	if err != nil {
+		return installOutput{}, err
+	}
+	PrintVerbose("Verified %d file(s) against the bundle manifest\n", len(b.Manifest.Files))
+
+	path, err := b.Install(templatesDir, name, force)
+	if err != nil {
+		return installOutput{}, err
+	}
+
+	return installOutput{
+		Name:     filepath.Base(path),
+		Version:  b.Manifest.Version,
+		Path:     path,
+		Files:    len(b.Manifest.Files),
+		Checksum: checksum,
+		Source:   source,
+	}, nil
+}
+
+func installFromLock(cfg *config.Config, templatesDir string) error {
+	lockPath := lockFilePath(cfg)
+	lock, err := registry.LoadLock(lockPath)
+	if err != nil {
+		return err
+	}
+	if len(lock.Templates) == 0 {
+		return fmt.Errorf("nothing to install: %s has no templates", lockPath)
+	}
+
+	output := installLockOutput{Lockfile: lockPath, Installed: []installOutput{}, UpToDate: []string{}}
+	for _, locked := range lock.Templates {
+		if installedVersion(templatesDir, locked.Name) == locked.Version && !installForce {
+			PrintVerbose("%s %s is up to date\n", locked.Name, locked.Version)
+			output.UpToDate = append(output.UpToDate, locked.Name)
+			continue
+		}
+
+		installed, err := installBundle(templatesDir, lockedSource(lockPath, locked.Source), locked.Checksum, locked.Name, true)
+		if err != nil {
+			return fmt.Errorf("failed to install %s %s: %w", locked.Name, locked.Version, err)
+		}
+		if installed.Version != locked.Version {
+			return fmt.Errorf("bundle for %s is version %s, but %s is locked", locked.Name, installed.Version, locked.Version)
+		}
+		output.Installed = append(output.Installed, installed)
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	for _, installed := range output.Installed {
+		printInstalled(installed)
+	}
+	if len(output.UpToDate) > 0 {
+		InfoColor.Printf("%d template(s) already up to date\n", len(output.UpToDate))
+	}
+	return nil
+}
+
+func installedVersion(templatesDir, name string) string {
+	tmpl, err := config.LoadTemplate(filepath.Join(templatesDir, name))
+	if err != nil {
+		return ""
+	}
+	return tmpl.Version
+}
+
+func printInstalled(output installOutput) {
+	SuccessColor.Printf("✓ Installed %s", output.Name)
+	if output.Version != "" {
+		fmt.Printf(" v%s", output.Version)
+	}
+	fmt.Printf(" to %s\n", BoldColor.Sprint(output.Path))
+}
+
+// lockFilePath places tg.lock next to the project config, or in the current
+// directory when there is none.
+func lockFilePath(cfg *config.Config) string {
+	if cfg.ProjectPath() == "" {
+		return registry.LockFile
+	}
+	return filepath.Join(filepath.Dir(cfg.ProjectPath()), registry.LockFile)
+}
+
+func isLocked(cfg *config.Config, name string) bool {
+	lock, err := registry.LoadLock(lockFilePath(cfg))
+	if err != nil {
+		return false
+	}
+	_, ok := lock.Get(name)
+	return ok
+}
+
+func lockInstall(cfg *config.Config, output installOutput) error {
+	lockPath := lockFilePath(cfg)
+	lock, err := registry.LoadLock(lockPath)
+	if err != nil {
+		return err
+	}
+
+	lock.Set(registry.Locked{
+		Name:     output.Name,
+		Version:  output.Version,
+		Source:   lockSource(lockPath, output.Source),
+		Checksum: output.Checksum,
+	})
+	if err := lock.Save(lockPath); err != nil {
+		return err
+	}
+	PrintVerbose("Recorded %s %s in %s\n", output.Name, output.Version, lockPath)
+	return nil
+}
+
+// lockSource records a local bundle path relative to the lockfile, the way
+// paths in tg.config.toml are relative to the config, so the lock works in
+// every checkout of the project. URLs are kept as they are.
+func lockSource(lockPath, source string) string {
+	if bundle.IsURL(source) {
+		return source
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return source
+	}
+	base, err := filepath.Abs(filepath.Dir(lockPath))
+	if err != nil {
+		return abs
+	}
+	relative, err := filepath.Rel(base, abs)
+	if err != nil {
+		return abs
+	}
+	return filepath.ToSlash(relative)
+}
+
+// lockedSource resolves a source recorded by lockSource.
+func lockedSource(lockPath, source string) string {
+	if bundle.IsURL(source) || filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(filepath.Dir(lockPath), filepath.FromSlash(source))
+}
+
+func installTemplatesDir(cfg *config.Config) (string, error) {
+	if installGlobal {
+		dir := config.UserTemplatesDir()
+		if dir == "" {
+			return "", fmt.Errorf("cannot determine the user templates directory")
+		}
+		return dir, nil
+	}
+	return cfg.TemplatesDir, nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLockSource(t *testing.T) {
+	project := t.TempDir()
+	lockPath := filepath.Join(project, "tg.lock")
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "url", source: "https://example.com/web-1.0.0.tgz", want: "https://example.com/web-1.0.0.tgz"},
+		{name: "registry in the project", source: filepath.Join(project, "registry", "web-1.0.0.tgz"), want: "registry/web-1.0.0.tgz"},
+		{name: "bundle next to the project", source: filepath.Join(filepath.Dir(project), "bundles", "web-1.0.0.tgz"), want: "../bundles/web-1.0.0.tgz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lockSource(lockPath, tt.source)
+			if got != tt.want {
+				t.Errorf("lockSource() = %q, want %q", got, tt.want)
+			}
+			if back := lockedSource(lockPath, got); back != tt.source {
+				t.Errorf("lockedSource(%q) = %q, want %q", got, back, tt.source)
+			}
+		})
+	}
+
+	absolute := filepath.Join(project, "old", "web-1.0.0.tgz")
+	if got := lockedSource(lockPath, absolute); got != absolute {
+		t.Errorf("lockedSource() of an absolute source = %q, want it unchanged", got)
+	}
+}
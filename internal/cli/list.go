@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"text/tabwriter"
 
@@ -24,7 +23,9 @@ func newListCommand() *cobra.Command {
 		Short:   "List available templates",
 		Long: `List displays all available templates in the configured template directory.
 
-Templates are loaded from the directory specified in tg.config.toml.
+Templates are loaded from every directory in template_paths (or templates_dir)
+from tg.config.toml, followed by the user-global templates directory. When two
+templates share a name, the one from the earlier path shadows the other.
 Each template must have a template.toml configuration file to be recognized.`,
 		Example: `  # List all templates
 tg list
@@ -36,12 +37,16 @@ tg list --details
 tg list --format table
 
 # Filter templates by name
-tg list --filter "web"`,
+tg list --filter "web"
+
+# Machine-readable output
+tg list --output json
+tg list --output yaml`,
 		RunE: runList,
 	}
 
 	cmd.Flags().BoolVarP(&listDetails, "details", "d", false, "Show detailed template information")
-	cmd.Flags().StringVarP(&listFormat, "format", "F", "list", "Output format: list, table, json")
+	cmd.Flags().StringVarP(&listFormat, "format", "F", "list", "Display format: list, table, json, yaml")
 	cmd.Flags().StringVarP(&listFilter, "filter", "f", "", "Filter templates by name (case-insensitive)")
 
 	return cmd
@@ -53,15 +58,16 @@ func runList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	templatesDir := cfg.TemplatesDir
-
-	if _, err := os.Stat(templatesDir); os.IsNotExist(err) {
-		WarnColor.Printf("Templates directory %s does not exist\n", templatesDir)
+	if err := cfg.Validate(); err != nil {
+		if IsMachineOutput() {
+			return err
+		}
+		WarnColor.Println(err)
 		fmt.Println("Run 'tg init' first to initialize the configuration")
 		return nil
 	}
 
-	templates, err := findTemplates(templatesDir)
+	templates, err := findTemplates(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to find templates: %w", err)
 	}
@@ -70,6 +76,15 @@ func runList(cmd *cobra.Command, args []string) error {
 		templates = filterTemplates(templates, listFilter)
 	}
 
+	format := listFormat
+	if IsMachineOutput() {
+		format = outputFormat
+	}
+
+	if format == OutputJSON || format == OutputYAML {
+		return displayTemplatesMachine(templates, format)
+	}
+
 	if len(templates) == 0 {
 		if listFilter != "" {
 			fmt.Printf("No templates found matching filter: %s\n", listFilter)
@@ -82,11 +97,9 @@ func runList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	switch listFormat {
+	switch format {
 	case "table":
 		return displayTemplatesTable(templates)
-	case "json":
-		return displayTemplatesJSON(templates)
 	default:
 		return displayTemplatesList(templates)
 	}
@@ -95,48 +108,35 @@ func runList(cmd *cobra.Command, args []string) error {
 type TemplateInfo struct {
 	Name        string
 	Path        string
+	Source      string
 	Description string
 	Author      string
 	Version     string
 	Variables   int
+	Shadows     []string
+	Config      *config.Template
 }
 
-func findTemplates(templatesDir string) ([]TemplateInfo, error) {
-	var templates []TemplateInfo
-
-	entries, err := os.ReadDir(templatesDir)
+func findTemplates(cfg *config.Config) ([]TemplateInfo, error) {
+	discovered, err := discoverTemplates(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		templatePath := filepath.Join(templatesDir, entry.Name())
-		configPath := filepath.Join(templatePath, "template.toml")
-
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			PrintVerbose("Skipping %s: no template.toml found\n", entry.Name())
-			continue
-		}
-
-		template, err := config.LoadTemplate(templatePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load template: %w", err)
-		}
-
-		info := TemplateInfo{
+	templates := make([]TemplateInfo, 0, len(discovered))
+	for _, found := range discovered {
+		template := found.Template
+		templates = append(templates, TemplateInfo{
 			Name:        template.Metadata.Name,
-			Path:        templatePath,
+			Path:        found.Dir,
+			Source:      found.Source,
 			Description: template.Metadata.Description,
 			Author:      template.Metadata.Author,
 			Version:     template.Version,
 			Variables:   len(template.Variables),
-		}
-
-		templates = append(templates, info)
+			Shadows:     found.Shadows,
+			Config:      template,
+		})
 	}
 
 	return templates, nil
@@ -160,11 +160,22 @@ func displayTemplatesList(templates []TemplateInfo) error {
 	InfoColor.Printf("Found %d template(s):\n", len(templates))
 	fmt.Println()
 
+	multipleSources := false
+	for _, tmpl := range templates {
+		if tmpl.Source != templates[0].Source {
+			multipleSources = true
+			break
+		}
+	}
+
 	for _, tmpl := range templates {
 		fmt.Printf("  • %s", BoldColor.Sprint(tmpl.Name))
-		if tmpl.Version != "" && tmpl.Version != "1.0.0" {
+		if tmpl.Version != "" {
 			fmt.Printf(" (v%s)", tmpl.Version)
 		}
+		if multipleSources {
+			fmt.Printf(" [%s]", tmpl.Source)
+		}
 		fmt.Println()
 
 		if listDetails {
@@ -176,6 +187,9 @@ func displayTemplatesList(templates []TemplateInfo) error {
 			}
 			fmt.Printf("    Variables: %d\n", tmpl.Variables)
 			fmt.Printf("    Path: %s\n", tmpl.Path)
+			for _, shadowed := range tmpl.Shadows {
+				fmt.Printf("    Shadows: %s\n", shadowed)
+			}
 			fmt.Println()
 		}
 	}
@@ -188,45 +202,38 @@ func displayTemplatesList(templates []TemplateInfo) error {
 	return nil
 }
 
-// TODO: use json library
-func displayTemplatesJSON(templates []TemplateInfo) error {
-	fmt.Println("{")
-	fmt.Printf("  \"templates\": [\n")
-	for i, tmpl := range templates {
-		fmt.Printf("    {\n")
-		fmt.Printf("      \"name\": \"%s\",\n", tmpl.Name)
-		fmt.Printf("      \"version\": \"%s\",\n", tmpl.Version)
-		fmt.Printf("      \"author\": \"%s\",\n", tmpl.Author)
-		fmt.Printf("      \"description\": \"%s\",\n", tmpl.Description)
-		fmt.Printf("      \"variables\": %d,\n", tmpl.Variables)
-		fmt.Printf("      \"path\": \"%s\"\n", tmpl.Path)
-		fmt.Printf("    }")
-		if i < len(templates)-1 {
-			fmt.Printf(",")
-		}
-		fmt.Println()
+type templateListOutput struct {
+	Templates []templateOutput `json:"templates"`
+}
+
+func displayTemplatesMachine(templates []TemplateInfo, format string) error {
+	output := templateListOutput{Templates: make([]templateOutput, 0, len(templates))}
+	for _, tmpl := range templates {
+		templateOutput := newTemplateOutput(tmpl.Path, tmpl.Config)
+		templateOutput.Source = tmpl.Source
+		templateOutput.Shadows = nonNil(tmpl.Shadows)
+		output.Templates = append(output.Templates, templateOutput)
 	}
-	fmt.Printf("  ]\n")
-	fmt.Println("}")
-	return nil
+	return encodeOutput(os.Stdout, format, output)
 }
 
 func displayTemplatesTable(templates []TemplateInfo) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 
-	fmt.Fprintln(w, "NAME\tVERSION\tAUTHOR\tVARIABLES\tDESCRIPTION")
-	fmt.Fprintln(w, "----\t-------\t------\t---------\t-----------")
+	fmt.Fprintln(w, "NAME\tVERSION\tAUTHOR\tVARIABLES\tSOURCE\tDESCRIPTION")
+	fmt.Fprintln(w, "----\t-------\t------\t---------\t------\t-----------")
 
 	for _, tmpl := range templates {
 		description := tmpl.Description
 		if len(description) > 40 && !listDetails {
 			description = description[:37] + "..."
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
 			tmpl.Name,
 			tmpl.Version,
 			tmpl.Author,
 			tmpl.Variables,
+			tmpl.Source,
 			description,
 		)
 	}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// templateOutput is the machine-readable description of a template shared by
+// every command that reports templates.
+type templateOutput struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	MinTGVersion string            `json:"min_tg_version"`
+	Author       string            `json:"author"`
+	Description  string            `json:"description"`
+	Path         string            `json:"path"`
+	Source       string            `json:"source,omitempty"`
+	Shadows      []string          `json:"shadows,omitempty"`
+	Extends      string            `json:"extends"`
+	Variables    []variableOutput  `json:"variables"`
+	Rules        rulesOutput       `json:"rules"`
+	Hooks        hooksOutput       `json:"hooks"`
+	Generators   []generatorOutput `json:"generators"`
+	Actions      []actionOutput    `json:"actions"`
+}
+
+type actionOutput struct {
+	File   string `json:"file"`
+	Op     string `json:"op"`
+	Anchor string `json:"anchor,omitempty"`
+}
+
+type variableOutput struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     any    `json:"default"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Choices     []any  `json:"choices"`
+	Pattern     string `json:"pattern"`
+	Group       string `json:"group"`
+	When        string `json:"when"`
+	Secret      bool   `json:"secret"`
+}
+
+type hooksOutput struct {
+	PreApply  []string `json:"pre_apply"`
+	PostApply []string `json:"post_apply"`
+}
+
+type rulesOutput struct {
+	Ignores  []string          `json:"ignores"`
+	Includes []string          `json:"includes"`
+	Renames  map[string]string `json:"renames"`
+}
+
+type errorOutput struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Message  string       `json:"message"`
+	Location *tg.Location `json:"location,omitempty"`
+}
+
+func newTemplateOutput(path string, tmpl *config.Template) templateOutput {
+	rules := rulesOutput{
+		Ignores:  nonNil(tmpl.Rules.Ignores),
+		Includes: nonNil(tmpl.Rules.Includes),
+		Renames:  tmpl.Rules.Renames,
+	}
+	if rules.Renames == nil {
+		rules.Renames = map[string]string{}
+	}
+
+	generators := make([]generatorOutput, 0, len(tmpl.Generators))
+	for _, name := range tmpl.GeneratorNames() {
+		generator := tmpl.Generators[name]
+		generators = append(generators, generatorOutput{
+			Name:        name,
+			Description: generator.Description,
+			Variables:   newVariablesOutput(generator.Variables),
+		})
+	}
+
+	actions := make([]actionOutput, 0, len(tmpl.Actions))
+	for _, action := range tmpl.Actions {
+		op, argument := action.Op()
+		output := actionOutput{File: action.File, Op: op}
+		if op != config.OpAppend && op != config.OpPrepend {
+			output.Anchor = argument
+		}
+		actions = append(actions, output)
+	}
+
+	return templateOutput{
+		Name:         tmpl.Metadata.Name,
+		Version:      tmpl.Version,
+		MinTGVersion: tmpl.MinTGVersion,
+		Author:       tmpl.Metadata.Author,
+		Description:  tmpl.Metadata.Description,
+		Path:         path,
+		Extends:      tmpl.Extends,
+		Variables:    newVariablesOutput(tmpl.Variables),
+		Rules:        rules,
+		Hooks: hooksOutput{
+			PreApply:  nonNil(tmpl.Hooks.PreApply),
+			PostApply: nonNil(tmpl.Hooks.PostApply),
+		},
+		Generators: generators,
+		Actions:    actions,
+	}
+}
+
+// newVariablesOutput lists variables in declaration order.
+func newVariablesOutput(declared map[string]config.Variable) []variableOutput {
+	variables := make([]variableOutput, 0, len(declared))
+	for _, variable := range config.OrderedVariables(declared) {
+		variables = append(variables, variableOutput{
+			Name:        variable.Name,
+			Type:        variable.Type,
+			Default:     variable.Default,
+			Description: variable.Description,
+			Required:    variable.Required,
+			Choices:     nonNil(variable.Choices),
+			Pattern:     variable.Pattern,
+			Group:       variable.Group,
+			When:        variable.When,
+			Secret:      variable.Secret,
+		})
+	}
+	return variables
+}
+
+// nonNil keeps empty lists as [] rather than null in the output schema
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+// IsMachineOutput returns true when --output asks for json or yaml
+func IsMachineOutput() bool {
+	return outputFormat == OutputJSON || outputFormat == OutputYAML
+}
+
+func validateOutputFormat() error {
+	switch outputFormat {
+	case OutputText, OutputJSON, OutputYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format '%s' (supported: text, json, yaml)", outputFormat)
+	}
+}
+
+// writeOutput prints value to stdout in the selected machine format
+func writeOutput(value any) error {
+	return encodeOutput(os.Stdout, outputFormat, value)
+}
+
+// writeErrorOutput prints err to stderr as an error object
+func writeErrorOutput(err error) {
+	detail := errorDetail{Message: err.Error()}
+	if location, ok := tg.ErrorLocation(err); ok {
+		detail.Location = &location
+	}
+	encodeOutput(os.Stderr, outputFormat, errorOutput{Error: detail})
+}
+
+// encodeOutput writes value as JSON or YAML. YAML is derived from the JSON
+// encoding so both formats share the same field names and ordering.
+func encodeOutput(w io.Writer, format string, value any) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+
+	if format != OutputYAML {
+		_, err := w.Write(buffer.Bytes())
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(buffer.Bytes(), &node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	resetNodeStyle(&node)
+
+	yamlEncoder := yaml.NewEncoder(w)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return yamlEncoder.Close()
+}
+
+// resetNodeStyle drops the flow style and quoting inherited from JSON so the
+// YAML encoder picks its usual block style.
+func resetNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetNodeStyle(child)
+	}
+}
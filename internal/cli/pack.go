@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/bundle"
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var packOutputPath string
+
+func newPackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack <template-name>",
+		Short: "Package a template as a .tgz bundle",
+		Long: `Pack writes a template as a versioned tar.gz bundle that can be shared and
+installed with 'tg install'.
+
+The bundle contains template.toml, every template file and a manifest with the
+sha256 checksum of each file. The bundle is named <name>-<version>.tgz unless
+--output-file is given.`,
+		Example: `  # Create web-app-1.2.0.tgz in the current directory
+  tg pack web-app
+
+  # Choose the bundle path
+  tg pack web-app -f dist/web-app.tgz`,
+		Args: cobra.ExactArgs(1),
+		RunE: runPack,
+	}
+
+	cmd.Flags().StringVarP(&packOutputPath, "output-file", "f", "", "Bundle path (default: <name>-<version>.tgz)")
+
+	return cmd
+}
+
+type packOutput struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Files    int    `json:"files"`
+	Checksum string `json:"checksum"`
+}
+
+func runPack(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	templateDir, tmpl, err := resolveTemplateDir(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := tmpl.Validate(); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	if tmpl.Extends != "" {
+		WarnColor.Fprintf(os.Stderr, "Warning: %s extends '%s', which must be installed separately\n", tmpl.Metadata.Name, tmpl.Extends)
+	}
+
+	var buffer bytes.Buffer
+	manifest, err := bundle.Pack(templateDir, tmpl, &buffer)
+	if err != nil {
+		return fmt.Errorf("failed to pack template: %w", err)
+	}
+
+	path := packOutputPath
+	if path == "" {
+		path = bundle.FileName(tmpl)
+	}
+	if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	output := packOutput{
+		Name:     manifest.Name,
+		Version:  manifest.Version,
+		Path:     path,
+		Files:    len(manifest.Files),
+		Checksum: bundle.Checksum(buffer.Bytes()),
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	SuccessColor.Println("✓ Template packed successfully!")
+	fmt.Printf("  Bundle:   %s\n", BoldColor.Sprint(output.Path))
+	fmt.Printf("  Files:    %d\n", output.Files)
+	fmt.Printf("  Checksum: %s\n", output.Checksum)
+	return nil
+}
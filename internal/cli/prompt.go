@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"golang.org/x/term"
+)
+
+// promptVariables asks for each variable not already given, in declaration
+// order, skipping those whose when condition does not hold for the answers
+// so far. known holds the values before prompting, given the values set on
+// the command line. It returns given with the answers added.
+func promptVariables(in io.Reader, out io.Writer, variables map[string]config.Variable, known, given map[string]any) (map[string]any, error) {
+	values := maps.Clone(known)
+	answers := maps.Clone(given)
+	if answers == nil {
+		answers = make(map[string]any)
+	}
+	reader := bufio.NewReader(in)
+
+	group := ""
+	for _, variable := range config.OrderedVariables(variables) {
+		if _, ok := given[variable.Name]; ok {
+			continue
+		}
+
+		active, err := variable.Active(values)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %w", variable.Name, err)
+		}
+		if !active {
+			PrintVerbose("Skipping %s: %s does not hold\n", variable.Name, variable.When)
+			continue
+		}
+
+		if variable.Group != group {
+			group = variable.Group
+			fmt.Fprintln(out)
+			BoldColor.Fprintln(out, group)
+		}
+
+		value, err := promptVariable(reader, in, out, variable, values[variable.Name])
+		if err != nil {
+			return nil, err
+		}
+		values[variable.Name] = value
+		answers[variable.Name] = value
+	}
+	return answers, nil
+}
+
+// promptVariable asks until the answer satisfies the variable's
+// constraints. An empty answer keeps current.
+func promptVariable(reader *bufio.Reader, in io.Reader, out io.Writer, variable config.NamedVariable, current any) (any, error) {
+	for {
+		InfoColor.Fprint(out, "? ")
+		fmt.Fprint(out, variable.Name)
+		if variable.Description != "" {
+			fmt.Fprintf(out, " - %s", variable.Description)
+		}
+		if len(variable.Choices) > 0 {
+			fmt.Fprintf(out, " %v", variable.Choices)
+		}
+		switch {
+		case variable.Type == "boolean":
+			if isTrue(current) {
+				fmt.Fprint(out, " [Y/n]")
+			} else {
+				fmt.Fprint(out, " [y/N]")
+			}
+		case current != nil && current != "" && variable.Secret:
+			fmt.Fprintf(out, " [%s]", config.Redacted)
+		case current != nil && current != "":
+			fmt.Fprintf(out, " [%v]", current)
+		}
+		fmt.Fprint(out, ": ")
+
+		line, err := readAnswer(reader, in, out, variable.Secret)
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(out)
+			return nil, fmt.Errorf("no value for '%s': input ended", variable.Name)
+		}
+		answer := strings.TrimSpace(line)
+
+		var value any = answer
+		switch {
+		case answer == "":
+			value = current
+		case variable.Type == "boolean":
+			switch strings.ToLower(answer) {
+			case "y", "yes", "true":
+				value = true
+			case "n", "no", "false":
+				value = false
+			default:
+				ErrorColor.Fprintln(out, "  Please answer y or n")
+				continue
+			}
+		}
+
+		if err := variable.ValidateValue(value); err != nil {
+			ErrorColor.Fprintf(out, "  %v\n", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// readAnswer reads one line. Answers for secret variables are not echoed
+// when in is a terminal.
+func readAnswer(reader *bufio.Reader, in io.Reader, out io.Writer, secret bool) (string, error) {
+	if file, ok := in.(*os.File); ok && secret && term.IsTerminal(int(file.Fd())) {
+		answer, err := term.ReadPassword(int(file.Fd()))
+		fmt.Fprintln(out)
+		return string(answer), err
+	}
+	return reader.ReadString('\n')
+}
+
+func isTrue(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/semver"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+)
+
+// templateChain is a template together with the templates it extends,
+// ordered from the base template to the requested one.
+type templateChain struct {
+	Dirs     []string
+	Template *tg.Template
+}
+
+func resolveTemplateChain(cfg *config.Config, requestedName string) (*templateChain, error) {
+	dir, tmpl, err := resolveTemplateDir(cfg, requestedName)
+	if err != nil {
+		return nil, err
+	}
+	return loadTemplateChain(cfg, dir, tmpl)
+}
+
+// loadTemplateChain layers tmpl, loaded from dir, over the templates it
+// extends.
+func loadTemplateChain(cfg *config.Config, dir string, tmpl *config.Template) (*templateChain, error) {
+	if err := checkTemplate(tmpl); err != nil {
+		return nil, err
+	}
+
+	chain := &templateChain{
+		Dirs:     []string{dir},
+		Template: tg.New(tmpl, os.DirFS(dir)),
+	}
+	seen := map[string]bool{dir: true}
+
+	for current := tmpl; current.Extends != ""; {
+		parentDir, parent, err := resolveTemplateDir(cfg, current.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("template '%s' extends '%s': %w", current.Metadata.Name, current.Extends, err)
+		}
+		if seen[parentDir] {
+			return nil, fmt.Errorf("template '%s' has a circular extends chain through '%s'", tmpl.Metadata.Name, current.Extends)
+		}
+		seen[parentDir] = true
+
+		if err := checkTemplate(parent); err != nil {
+			return nil, err
+		}
+
+		chain.Dirs = append([]string{parentDir}, chain.Dirs...)
+		chain.Template = chain.Template.Extend(tg.New(parent, os.DirFS(parentDir)))
+		current = parent
+	}
+
+	return chain, nil
+}
+
+// Dir returns the directory of the requested template.
+func (chain *templateChain) Dir() string {
+	return chain.Dirs[len(chain.Dirs)-1]
+}
+
+// Names returns the template names from the base template to the requested one.
+func (chain *templateChain) Names() []string {
+	configs := chain.Template.Chain()
+	names := make([]string, 0, len(configs))
+	for _, t := range configs {
+		names = append(names, t.Metadata.Name)
+	}
+	return names
+}
+
+// warnedVersions holds the templates whose version was already reported as
+// not semantic, so watch mode does not repeat it on every render.
+var warnedVersions = make(map[string]bool)
+
+// checkTemplate refuses a template that needs a newer tg and warns about a
+// version that is not a semantic version.
+func checkTemplate(tmpl *config.Template) error {
+	if err := tmpl.CheckCompatibility(Version); err != nil {
+		return err
+	}
+	if tmpl.Version != "" && !warnedVersions[tmpl.Metadata.Name] {
+		if _, err := semver.Parse(tmpl.Version); err != nil {
+			warnedVersions[tmpl.Metadata.Name] = true
+			WarnColor.Fprintf(os.Stderr, "Template %s has version '%s', which is not a semantic version (MAJOR.MINOR.PATCH)\n", tmpl.Metadata.Name, tmpl.Version)
+		}
+	}
+	return nil
+}
+
+// resolveTemplateDir looks a template up by directory name, then by
+// metadata name, in each search path in order. The first search path with a
+// match wins. "name@constraint" picks the highest version that satisfies the
+// constraint, for templates installed side by side.
+func resolveTemplateDir(cfg *config.Config, requestedName string) (string, *config.Template, error) {
+	searchPaths := cfg.SearchPaths()
+
+	name, constraint, err := parseTemplateReference(requestedName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var installed []string
+	for _, searchPath := range searchPaths {
+		// A directory may itself be named "name@version"
+		if constraint != nil {
+			dir, tmpl, err := resolveTemplateIn(searchPath, requestedName, nil, nil)
+			if err != nil {
+				return "", nil, err
+			}
+			if tmpl != nil {
+				return dir, tmpl, nil
+			}
+		}
+
+		dir, tmpl, err := resolveTemplateIn(searchPath, name, constraint, &installed)
+		if err != nil {
+			return "", nil, err
+		}
+		if tmpl != nil {
+			return dir, tmpl, nil
+		}
+	}
+
+	if constraint != nil && len(installed) > 0 {
+		return "", nil, fmt.Errorf("no installed version of template '%s' matches '%s' (installed: %s)", name, constraint, strings.Join(installed, ", "))
+	}
+	return "", nil, fmt.Errorf("template '%s' not found in '%s'", name, strings.Join(searchPaths, "', '"))
+}
+
+func parseTemplateReference(requestedName string) (string, *semver.Constraint, error) {
+	name, constraintText, versioned := strings.Cut(requestedName, "@")
+	if !versioned {
+		return name, nil, nil
+	}
+
+	constraint, err := semver.ParseConstraint(constraintText)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, constraint, nil
+}
+
+// resolveTemplateIn finds name in one search path, matching the directory
+// name or the metadata name. When several versions match, the highest one
+// that satisfies constraint is used. Versions that fail the constraint are
+// appended to installed for error reporting.
+func resolveTemplateIn(searchPath, name string, constraint *semver.Constraint, installed *[]string) (string, *config.Template, error) {
+	candidateDir := filepath.Join(searchPath, name)
+
+	// Nested paths such as "group/web" name a directory directly
+	if strings.ContainsRune(filepath.ToSlash(name), '/') {
+		if _, err := os.Stat(filepath.Join(candidateDir, config.TemplateConfigFile)); err != nil {
+			return "", nil, nil
+		}
+		tmpl, err := config.LoadTemplate(candidateDir)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to load template: %w", err)
+		}
+		if constraint != nil {
+			if version, ok := templateVersion(tmpl); !ok || !constraint.Check(version) {
+				return "", nil, nil
+			}
+		}
+		return candidateDir, tmpl, nil
+	}
+
+	dirs, err := templateDirsIn(searchPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		bestDir string
+		best    *config.Template
+	)
+	for _, dir := range dirs {
+		var tmpl *config.Template
+		if dir == candidateDir {
+			// The template asked for by directory name must load
+			if tmpl, err = config.LoadTemplate(dir); err != nil {
+				return "", nil, fmt.Errorf("failed to load template: %w", err)
+			}
+		} else if tmpl = loadSearchedTemplate(dir); tmpl == nil || tmpl.Metadata.Name != name {
+			continue
+		}
+
+		if constraint != nil {
+			version, ok := templateVersion(tmpl)
+			if !ok || !constraint.Check(version) {
+				if installed != nil {
+					*installed = append(*installed, tmpl.Version)
+				}
+				continue
+			}
+		}
+
+		if best == nil || newerTemplate(tmpl, best) {
+			bestDir, best = dir, tmpl
+		}
+	}
+
+	return bestDir, best, nil
+}
+
+func templateVersion(tmpl *config.Template) (semver.Version, bool) {
+	version, err := semver.Parse(tmpl.Version)
+	return version, err == nil
+}
+
+// newerTemplate reports whether a has a higher version than b. Templates
+// without a valid version sort below every versioned one.
+func newerTemplate(a, b *config.Template) bool {
+	versionA, okA := templateVersion(a)
+	versionB, okB := templateVersion(b)
+	switch {
+	case !okA:
+		return false
+	case !okB:
+		return true
+	default:
+		return versionB.LessThan(versionA)
+	}
+}
+
+// templateDirsIn lists the template directories directly inside searchPath.
+// A search path that does not exist simply holds no templates.
+func templateDirsIn(searchPath string) ([]string, error) {
+	entries, err := os.ReadDir(searchPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(searchPath, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, config.TemplateConfigFile)); err != nil {
+			PrintVerbose("Skipping %s: no %s found\n", dir, config.TemplateConfigFile)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// skippedTemplates holds the directories loadSearchedTemplate warned about,
+// so each is reported once per command.
+var skippedTemplates = make(map[string]bool)
+
+// loadSearchedTemplate loads a template found while searching the search
+// paths. One that cannot be loaded is skipped with a warning and nil is
+// returned, so a broken template does not hide every other one.
+func loadSearchedTemplate(dir string) *config.Template {
+	tmpl, err := config.LoadTemplate(dir)
+	if err != nil {
+		if !skippedTemplates[dir] {
+			skippedTemplates[dir] = true
+			WarnColor.Fprintf(os.Stderr, "Skipping template in %s: %v\n", dir, err)
+		}
+		return nil
+	}
+	return tmpl
+}
+
+// discoveredTemplate is a template found in one of the search paths.
+type discoveredTemplate struct {
+	Dir      string
+	Source   string
+	Template *config.Template
+
+	// Shadows lists templates with the same name in later search paths
+	Shadows []string
+}
+
+// discoverTemplates loads every template in the search paths. When several
+// templates share a name, the one found first wins and hides the others.
+func discoverTemplates(cfg *config.Config) ([]*discoveredTemplate, error) {
+	var templates []*discoveredTemplate
+	byName := make(map[string]*discoveredTemplate)
+
+	for _, searchPath := range cfg.SearchPaths() {
+		dirs, err := templateDirsIn(searchPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			tmpl := loadSearchedTemplate(dir)
+			if tmpl == nil {
+				continue
+			}
+
+			if winner, ok := byName[tmpl.Metadata.Name]; ok {
+				// Side-by-side versions in one search path: the newest is listed
+				if winner.Source == searchPath && newerTemplate(tmpl, winner.Template) {
+					PrintVerbose("Template %s in %s is shadowed by newer version in %s\n", tmpl.Metadata.Name, winner.Dir, dir)
+					winner.Shadows = append(winner.Shadows, winner.Dir)
+					winner.Dir, winner.Template = dir, tmpl
+					continue
+				}
+
+				PrintVerbose("Template %s in %s is shadowed by %s\n", tmpl.Metadata.Name, dir, winner.Dir)
+				winner.Shadows = append(winner.Shadows, dir)
+				continue
+			}
+
+			discovered := &discoveredTemplate{
+				Dir:      dir,
+				Source:   searchPath,
+				Template: tmpl,
+			}
+			byName[tmpl.Metadata.Name] = discovered
+			templates = append(templates, discovered)
+		}
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func writeTemplate(t *testing.T, dir, templateConfig string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.TemplateConfigFile), []byte(templateConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveSkipsBrokenTemplates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates := t.TempDir()
+	writeTemplate(t, filepath.Join(templates, "app"), "version = \"1.0.0\"\n")
+	writeTemplate(t, filepath.Join(templates, "side"), "version = \"1.2.0\"\n[metadata]\nname = \"app\"\n")
+	writeTemplate(t, filepath.Join(templates, "broken"), "version = [\n")
+	writeTemplate(t, filepath.Join(templates, "old"), "min_tg_version = \"1.0\"\n")
+	cfg := &config.Config{TemplatesDir: templates}
+
+	dir, tmpl, err := resolveTemplateDir(cfg, "app")
+	if err != nil {
+		t.Fatalf("resolveTemplateDir() error = %v", err)
+	}
+	if dir != filepath.Join(templates, "side") || tmpl.Version != "1.2.0" {
+		t.Errorf("resolveTemplateDir() = %s %s, want the newest version in side", dir, tmpl.Version)
+	}
+
+	discovered, err := discoverTemplates(cfg)
+	if err != nil {
+		t.Fatalf("discoverTemplates() error = %v", err)
+	}
+	if len(discovered) != 1 || discovered[0].Template.Metadata.Name != "app" {
+		t.Errorf("discoverTemplates() found %d template(s), want only app", len(discovered))
+	}
+
+	// A broken template asked for by name is still an error
+	if _, _, err := resolveTemplateDir(cfg, "broken"); err == nil {
+		t.Error("resolveTemplateDir(broken) succeeded, want an error")
+	}
+}
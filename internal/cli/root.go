@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -15,8 +17,9 @@ var (
 	Date    = "unknown"
 
 	// Global flags
-	verbose    bool
-	configPath string
+	verbose      bool
+	configPath   string
+	outputFormat string
 
 	// Color outputs
 	SuccessColor = color.New(color.FgGreen)
@@ -38,13 +41,32 @@ Templates are defined using TOML configuration files and can include:
   - Custom rename patterns
   - Git repository integration (coming soon)`,
 		Version: fmt.Sprintf("%s (commit: %s, built: %s)", Version, Commit, Date),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			legacyApplyOutput(cmd)
+			if err := validateOutputFormat(); err != nil {
+				return err
+			}
+			if IsMachineOutput() {
+				// Errors are reported as structured output by Execute
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			return nil
+		},
 	}
 )
 
 // Execute runs the root command
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
-		ErrorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		if IsMachineOutput() {
+			writeErrorOutput(err)
+		} else {
+			ErrorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			if location, ok := tg.ErrorLocation(err); ok {
+				fmt.Fprint(os.Stderr, location.Caret())
+			}
+		}
 		return err
 	}
 	return nil
@@ -53,16 +75,28 @@ func Execute() error {
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "V", false, "Enable verbose output")
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "tg.config.toml", "Path to config file")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default: nearest tg.config.toml)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", OutputText, "Output format: text, json, yaml")
 
 	// Add commands
 	rootCmd.AddCommand(
 		newInitCommand(),
 		newListCommand(),
 		newApplyCommand(),
+		newValidateCommand(),
+		newInfoCommand(),
+		newConfigCommand(),
+		newPackCommand(),
+		newInstallCommand(),
+		newSearchCommand(),
+		newGenCommand(),
+		newTestCommand(),
+		newDiffCommand(),
+		newStatusCommand(),
+		newUpgradeCommand(),
+		newExtractCommand(),
 	// newNewCommand(),
 	// newFetchCommand(), // for git integration
-	// newValidateCommand(), // for template validation
 	)
 
 	// Custom version template
@@ -75,14 +109,27 @@ func IsVerbose() bool {
 	return verbose
 }
 
-// GetConfigPath returns the config file path
+// GetConfigPath returns the config file path given with --config, or the
+// default file name in the current directory
 func GetConfigPath() string {
+	if configPath == "" {
+		return config.DefaultConfigFile
+	}
 	return configPath
 }
 
-// PrintVerbose prints message only in verbose mode
+// stdoutReserved is set by commands that write generated data to stdout.
+var stdoutReserved bool
+
+// PrintVerbose prints message only in verbose mode. When stdout is reserved
+// for a machine-readable result or generated data, messages go to stderr.
 func PrintVerbose(format string, args ...interface{}) {
-	if IsVerbose() {
-		fmt.Printf(format, args...)
+	if !IsVerbose() {
+		return
+	}
+	if IsMachineOutput() || stdoutReserved {
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
 	}
+	fmt.Printf(format, args...)
 }
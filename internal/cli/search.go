@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+func newSearchCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search the template registry",
+		Long: `Search lists templates in the configured registry whose name, description or
+tags contain the query. Without a query every template is listed.
+
+The registry is set with 'tg config set registry <url-or-path>'.`,
+		Example: `  # Find templates for web services
+  tg search web
+
+  # List the whole registry as JSON
+  tg search --output json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runSearch,
+	}
+}
+
+type searchResultOutput struct {
+	Name        string   `json:"name"`
+	Latest      string   `json:"latest"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Versions    []string `json:"versions"`
+}
+
+type searchOutput struct {
+	Registry  string               `json:"registry"`
+	Templates []searchResultOutput `json:"templates"`
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := ""
+	if len(args) > 0 {
+		query = args[0]
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	index, err := loadRegistry(cfg)
+	if err != nil {
+		return err
+	}
+
+	output := searchOutput{Registry: cfg.Registry, Templates: []searchResultOutput{}}
+	for _, entry := range index.Search(query) {
+		result := searchResultOutput{
+			Name:        entry.Name,
+			Description: entry.Description,
+			Tags:        nonNil(entry.Tags),
+			Versions:    make([]string, 0, len(entry.Versions)),
+		}
+		if latest, ok := entry.Latest(); ok {
+			result.Latest = latest.Version
+		}
+		for _, release := range entry.Versions {
+			result.Versions = append(result.Versions, release.Version)
+		}
+		output.Templates = append(output.Templates, result)
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+
+	if len(output.Templates) == 0 {
+		WarnColor.Printf("No templates matching '%s' in %s\n", query, cfg.Registry)
+		return nil
+	}
+
+	for _, result := range output.Templates {
+		BoldColor.Print(result.Name)
+		if result.Latest != "" {
+			fmt.Printf(" v%s", result.Latest)
+		}
+		fmt.Println()
+		if result.Description != "" {
+			fmt.Printf("  %s\n", result.Description)
+		}
+		if len(result.Tags) > 0 {
+			InfoColor.Printf("  tags: %s\n", strings.Join(result.Tags, ", "))
+		}
+	}
+	return nil
+}
+
+func loadRegistry(cfg *config.Config) (*registry.Index, error) {
+	if cfg.Registry == "" {
+		return nil, fmt.Errorf("no registry configured. Set one with 'tg config set registry <url>'")
+	}
+
+	PrintVerbose("Loading registry: %s\n", cfg.Registry)
+	return registry.LoadIndex(cfg.Registry)
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/internal/semver"
+	"github.com/spf13/cobra"
+)
+
+var statusExitCode bool
+
+func newStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [dir]",
+		Short: "Show how a generated project has changed since it was generated",
+		Long: `Status reads the manifest that 'tg apply' writes to a generated directory,
+which defaults to the current directory, and reports for every template
+applied there:
+
+  - generated files that were modified by hand
+  - generated files that were deleted
+  - whether a newer version of the template is in the templates directory
+
+The command only reads files; use 'tg diff' to see the changes themselves.`,
+		Example: `  # Check the current project
+  tg status
+
+  # Report on another project as JSON, e.g. from a nightly job
+  tg status ./services/api --output json
+
+  # Fail when anything has drifted or an update is available
+  tg status --exit-code`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runStatus,
+	}
+
+	cmd.Flags().BoolVar(&statusExitCode, "exit-code", false, "Exit with a non-zero status when files changed or an update is available")
+
+	return cmd
+}
+
+type statusOutput struct {
+	Dir       string                 `json:"dir"`
+	Clean     bool                   `json:"clean"`
+	Templates []templateStatusOutput `json:"templates"`
+}
+
+type templateStatusOutput struct {
+	Name            string    `json:"name"`
+	Version         string    `json:"version"`
+	Latest          string    `json:"latest,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	Error           string    `json:"error,omitempty"`
+	GeneratedAt     time.Time `json:"generated_at"`
+	Modified        []string  `json:"modified"`
+	Deleted         []string  `json:"deleted"`
+	Unchanged       int       `json:"unchanged"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	if len(m.Templates) == 0 {
+		return fmt.Errorf("no %s in '%s' (was it generated with 'tg apply'?)", manifest.FileName, dir)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	output := statusOutput{
+		Dir:       dir,
+		Clean:     true,
+		Templates: make([]templateStatusOutput, 0, len(m.Templates)),
+	}
+	for _, entry := range m.Templates {
+		status, err := templateStatus(cfg, dir, entry)
+		if err != nil {
+			return err
+		}
+		if len(status.Modified) > 0 || len(status.Deleted) > 0 || status.UpdateAvailable {
+			output.Clean = false
+		}
+		output.Templates = append(output.Templates, status)
+	}
+
+	if IsMachineOutput() {
+		if err := writeOutput(output); err != nil {
+			return err
+		}
+	} else {
+		displayStatus(output)
+	}
+
+	if statusExitCode && !output.Clean {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("'%s' has changed since it was generated", dir)
+	}
+	return nil
+}
+
+func templateStatus(cfg *config.Config, dir string, entry manifest.Entry) (templateStatusOutput, error) {
+	status := templateStatusOutput{
+		Name:        entry.Name,
+		Version:     entry.Version,
+		GeneratedAt: entry.GeneratedAt,
+		Modified:    []string{},
+		Deleted:     []string{},
+	}
+
+	states, err := entry.Check(dir)
+	if err != nil {
+		return status, err
+	}
+	for path, state := range states {
+		switch state {
+		case manifest.FileModified:
+			status.Modified = append(status.Modified, path)
+		case manifest.FileDeleted:
+			status.Deleted = append(status.Deleted, path)
+		default:
+			status.Unchanged++
+		}
+	}
+	sort.Strings(status.Modified)
+	sort.Strings(status.Deleted)
+
+	// A template missing from this machine is reported, not fatal, so a
+	// nightly run still covers every other template
+	_, latest, err := resolveTemplateDir(cfg, entry.Name)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	status.Latest = latest.Version
+	status.UpdateAvailable = isNewerVersion(latest.Version, entry.Version)
+	return status, nil
+}
+
+// isNewerVersion reports whether latest is a higher version than current.
+// A project generated from an unversioned template is behind any release.
+func isNewerVersion(latest, current string) bool {
+	latestVersion, err := semver.Parse(latest)
+	if err != nil {
+		return false
+	}
+	currentVersion, err := semver.Parse(current)
+	if err != nil {
+		return current == ""
+	}
+	return currentVersion.LessThan(latestVersion)
+}
+
+func displayStatus(output statusOutput) {
+	InfoColor.Printf("Status of %s\n", BoldColor.Sprint(filepath.Clean(output.Dir)))
+
+	for _, tmpl := range output.Templates {
+		fmt.Println()
+		BoldColor.Print(tmpl.Name)
+		if tmpl.Version != "" {
+			fmt.Printf(" v%s", tmpl.Version)
+		}
+		fmt.Printf(" (generated %s)\n", tmpl.GeneratedAt.Local().Format("2006-01-02 15:04"))
+
+		switch {
+		case tmpl.Error != "":
+			WarnColor.Printf("  ! %s\n", tmpl.Error)
+		case tmpl.UpdateAvailable:
+			WarnColor.Printf("  ↑ v%s is available\n", tmpl.Latest)
+		}
+
+		for _, path := range tmpl.Modified {
+			WarnColor.Printf("  %-9s", "modified")
+			fmt.Printf(" %s\n", path)
+		}
+		for _, path := range tmpl.Deleted {
+			ErrorColor.Printf("  %-9s", "deleted")
+			fmt.Printf(" %s\n", path)
+		}
+		fmt.Printf("  %d modified, %d deleted, %d unchanged\n", len(tmpl.Modified), len(tmpl.Deleted), tmpl.Unchanged)
+	}
+
+	fmt.Println()
+	if output.Clean {
+		SuccessColor.Println("✓ Up to date, no generated files changed")
+		return
+	}
+	fmt.Println("Run 'tg diff <template-name>' to see how files differ from the template")
+}
@@ -0,0 +1,352 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+)
+
+var testUpdate bool
+
+// File statuses reported for a failing test case
+const (
+	testFileChanged    = "changed"
+	testFileMissing    = "missing"
+	testFileUnexpected = "unexpected"
+)
+
+func newTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [template-name...]",
+		Short: "Run golden-file tests for templates",
+		Long: `Test renders each test case of a template in memory and compares the result
+with the case's golden files.
+
+A template opts in with dir under [tests] in template.toml, usually "tests".
+A case is a <dir>/<case>.toml file with a [variables] table. Its expected
+output lives in <dir>/<case>/expected/, and files the output directory
+starts with (for actions that patch existing files) in <dir>/<case>/input/.
+The tests directory is not part of the template's output.
+
+All templates with tests are run unless names are given. The command exits
+with a non-zero status if any case fails.`,
+		Example: `  # Run every template's tests
+  tg test
+
+  # Run one template's tests and print the result as JSON
+  tg test web-app --output json
+
+  # Write the current output as the new golden files
+  tg test web-app --update`,
+		RunE: runTest,
+	}
+
+	cmd.Flags().BoolVarP(&testUpdate, "update", "u", false, "Regenerate the expected files from the current output")
+
+	return cmd
+}
+
+type testOutput struct {
+	Passed    bool                 `json:"passed"`
+	Templates []templateTestOutput `json:"templates"`
+}
+
+type templateTestOutput struct {
+	Name  string           `json:"name"`
+	Path  string           `json:"path"`
+	Error string           `json:"error,omitempty"`
+	Cases []testCaseOutput `json:"cases"`
+}
+
+type testCaseOutput struct {
+	Name    string           `json:"name"`
+	Passed  bool             `json:"passed"`
+	Updated bool             `json:"updated,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Files   []testFileOutput `json:"files"`
+}
+
+type testFileOutput struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+func runTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	dirs, err := validateTargets(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	output := testOutput{
+		Passed:    true,
+		Templates: make([]templateTestOutput, 0, len(dirs)),
+	}
+	total, failed := 0, 0
+	for _, dir := range dirs {
+		tmpl, err := config.LoadTemplate(dir)
+		var cases []config.TestCase
+		if err == nil {
+			cases, err = config.LoadTestCases(os.DirFS(dir), tmpl.Tests)
+		}
+		if err == nil && len(cases) == 0 && len(args) == 0 {
+			// Without names, only templates that have tests are run
+			continue
+		}
+
+		result := testTemplate(cfg, dir, tmpl, cases, err)
+		if result.Error != "" {
+			total++
+			failed++
+		}
+		for _, testCase := range result.Cases {
+			total++
+			if !testCase.Passed {
+				failed++
+			}
+		}
+		output.Templates = append(output.Templates, result)
+	}
+	output.Passed = failed == 0
+
+	if IsMachineOutput() {
+		if err := writeOutput(output); err != nil {
+			return err
+		}
+	} else {
+		displayTestResult(output, total, failed)
+	}
+
+	if !output.Passed {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d test case(s) failed", failed, total)
+	}
+	return nil
+}
+
+// testTemplate runs the cases of the template in dir. loadErr is the error
+// loading the template or its cases, if any.
+func testTemplate(cfg *config.Config, dir string, tmpl *config.Template, cases []config.TestCase, loadErr error) templateTestOutput {
+	result := templateTestOutput{
+		Name:  filepath.Base(dir),
+		Path:  dir,
+		Cases: []testCaseOutput{},
+	}
+	if tmpl != nil {
+		result.Name = tmpl.Metadata.Name
+	}
+
+	switch {
+	case loadErr != nil:
+		result.Error = loadErr.Error()
+		return result
+	case tmpl.Tests.Dir == "":
+		result.Error = "no tests (set dir under [tests] in template.toml)"
+		return result
+	case len(cases) == 0:
+		result.Error = fmt.Sprintf("no test cases (add %s/<case>.toml)", tmpl.Tests.Dir)
+		return result
+	}
+
+	chain, err := loadTemplateChain(cfg, dir, tmpl)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	for _, testCase := range cases {
+		PrintVerbose("Running %s/%s\n", result.Name, testCase.Name)
+		result.Cases = append(result.Cases, runTestCase(chain.Template, dir, testCase))
+	}
+	return result
+}
+
+func runTestCase(tmpl *tg.Template, dir string, testCase config.TestCase) testCaseOutput {
+	result := testCaseOutput{Name: testCase.Name, Files: []testFileOutput{}}
+
+	actual, err := renderTestCase(tmpl, dir, testCase)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	expectedDir := filepath.Join(dir, filepath.FromSlash(testCase.ExpectedDir()))
+	if testUpdate {
+		if err := writeGoldenFiles(expectedDir, actual); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Passed = true
+		result.Updated = true
+		return result
+	}
+
+	if _, err := os.Stat(expectedDir); err != nil {
+		result.Error = fmt.Sprintf("no expected files in %s (run with --update to create them)", testCase.ExpectedDir())
+		return result
+	}
+
+	expected, err := readTree(os.DirFS(expectedDir))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read expected files: %v", err)
+		return result
+	}
+
+	result.Files = compareTrees(expected, actual)
+	result.Passed = len(result.Files) == 0
+	return result
+}
+
+// renderTestCase applies the case in memory over its input files and
+// returns the whole resulting tree, keyed by slash-separated path.
+func renderTestCase(tmpl *tg.Template, dir string, testCase config.TestCase) (map[string][]byte, error) {
+	values, err := tmpl.Resolve(testCase.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	var input fs.FS
+	inputDir := filepath.Join(dir, filepath.FromSlash(testCase.InputDir()))
+	if _, err := os.Stat(inputDir); err == nil {
+		input = os.DirFS(inputDir)
+		if files, err = readTree(input); err != nil {
+			return nil, fmt.Errorf("failed to read input files: %w", err)
+		}
+	}
+
+	sink := tg.NewMemorySink(input)
+	if _, err := tmpl.ApplyTo(sink, values); err != nil {
+		return nil, err
+	}
+
+	for name, content := range sink.Files() {
+		files[filepath.ToSlash(name)] = content
+	}
+	return files, nil
+}
+
+func readTree(fsys fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = content
+		return nil
+	})
+	return files, err
+}
+
+func writeGoldenFiles(dir string, files map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old expected files: %w", err)
+	}
+
+	for name, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to write expected file %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// compareTrees lists every file that differs between expected and actual,
+// sorted by path.
+func compareTrees(expected, actual map[string][]byte) []testFileOutput {
+	paths := make([]string, 0, len(expected)+len(actual))
+	for name := range expected {
+		paths = append(paths, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+
+	files := []testFileOutput{}
+	for _, name := range paths {
+		want, inExpected := expected[name]
+		got, inActual := actual[name]
+
+		switch {
+		case !inActual:
+			files = append(files, testFileOutput{Path: name, Status: testFileMissing})
+		case !inExpected:
+			files = append(files, testFileOutput{Path: name, Status: testFileUnexpected})
+		case !bytes.Equal(want, got):
+			files = append(files, testFileOutput{Path: name, Status: testFileChanged, Diff: contentDiff(path.Join("expected", name), path.Join("actual", name), want, got)})
+		}
+	}
+	return files
+}
+
+func displayTestResult(output testOutput, total, failed int) {
+	for _, tmpl := range output.Templates {
+		BoldColor.Print(tmpl.Name)
+		fmt.Printf(" (%s)\n", tmpl.Path)
+		if tmpl.Error != "" {
+			ErrorColor.Printf("  ✗ %s\n", tmpl.Error)
+			continue
+		}
+
+		for _, testCase := range tmpl.Cases {
+			switch {
+			case testCase.Updated:
+				InfoColor.Printf("  ↻ %s", testCase.Name)
+				fmt.Println(" (expected files updated)")
+				continue
+			case testCase.Passed:
+				SuccessColor.Printf("  ✓ %s\n", testCase.Name)
+				continue
+			}
+
+			ErrorColor.Printf("  ✗ %s\n", testCase.Name)
+			if testCase.Error != "" {
+				fmt.Printf("      %s\n", testCase.Error)
+			}
+			for _, file := range testCase.Files {
+				fmt.Printf("      %s: %s\n", file.Status, file.Path)
+				printDiff(file.Diff, "        ")
+			}
+		}
+	}
+
+	if len(output.Templates) == 0 {
+		fmt.Println("No templates with tests found.")
+		return
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		ErrorColor.Printf("✗ %d of %d test case(s) failed\n", failed, total)
+		return
+	}
+	SuccessColor.Printf("✓ %d test case(s) passed\n", total)
+}
@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeTemplate   string
+	upgradeTo         string
+	upgradeVariables  map[string]string
+	upgradeVarFiles   map[string]string
+	upgradeVarEnvs    map[string]string
+	upgradeForce      bool
+	upgradeDryRun     bool
+	upgradeAllowHooks bool
+)
+
+func newUpgradeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade [dir]",
+		Short: "Upgrade a generated project to a newer template version",
+		Long: `Upgrade moves a generated project, the current directory by default, to the
+newest version of each template recorded in its manifest.
+
+The [[migrations]] declared by the new version are chained from the recorded
+version: their steps rename paths and variables, delete files and run
+commands in the project. The template is then rendered again with the
+recorded variables, the generators recorded by tg gen run again, and the
+manifest is updated. Secret variables are not
+recorded, so pass them again with --var, --var-file or --var-env.
+
+Re-rendering overwrites generated files, so upgrade refuses to run when any
+was modified by hand, unless --force is given. Commit or stash your work
+first: migration steps change the project directly. Each step is recorded in
+the manifest as it completes, so running upgrade again after a failure
+resumes after the last step that ran.
+
+Migration run steps execute shell commands from the template, so upgrade
+refuses to start when any would run unless --allow-hooks is given. Review
+them with --dry-run first.`,
+		Example: `  # Upgrade every template applied to the current project
+  tg upgrade
+
+  # Upgrade one template to the newest 2.x release
+  tg upgrade ./services/api --template web-app --to 2.x
+
+  # Show the migration steps without changing anything
+  tg upgrade --dry-run
+
+  # Run the commands of the migrations' run steps too
+  tg upgrade --allow-hooks`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runUpgrade,
+	}
+
+	cmd.Flags().StringVarP(&upgradeTemplate, "template", "t", "", "Only upgrade this template")
+	cmd.Flags().StringVar(&upgradeTo, "to", "", "Version constraint to upgrade to (default: newest installed version)")
+	cmd.Flags().StringToStringVarP(&upgradeVariables, "var", "v", nil, "Set variable values, e.g. ones the new version adds")
+	cmd.Flags().StringToStringVar(&upgradeVarFiles, "var-file", nil, "Read a variable's value from a file, e.g. a secret")
+	cmd.Flags().StringToStringVar(&upgradeVarEnvs, "var-env", nil, "Read a variable's value from an environment variable, e.g. a secret")
+	cmd.Flags().BoolVar(&upgradeForce, "force", false, "Upgrade even if generated files were modified by hand")
+	cmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "List the migrations that would run without changing anything")
+	cmd.Flags().BoolVar(&upgradeAllowHooks, "allow-hooks", false, "Run the shell commands of migration run steps")
+
+	return cmd
+}
+
+type upgradeOutput struct {
+	Dir       string                  `json:"dir"`
+	DryRun    bool                    `json:"dry_run"`
+	Templates []templateUpgradeOutput `json:"templates"`
+}
+
+type templateUpgradeOutput struct {
+	Name       string               `json:"name"`
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	UpToDate   bool                 `json:"up_to_date"`
+	Migrations []tg.MigrationResult `json:"migrations"`
+	Summary    applyReportSummary   `json:"summary"`
+	Files      []tg.FileResult      `json:"files"`
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	if len(m.Templates) == 0 {
+		return fmt.Errorf("no %s in '%s' (was it generated with 'tg apply'?)", manifest.FileName, dir)
+	}
+
+	var entries []manifest.Entry
+	if upgradeTemplate != "" {
+		entry, ok := m.Get(upgradeTemplate)
+		if !ok {
+			return fmt.Errorf("template '%s' was not applied to '%s'", upgradeTemplate, dir)
+		}
+		entries = append(entries, entry)
+	} else {
+		entries = m.Templates
+		if upgradeTo != "" && len(entries) > 1 {
+			return fmt.Errorf("--to needs --template when several templates were applied")
+		}
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	overrides, err := variableOverrides(upgradeVariables, upgradeVarFiles, upgradeVarEnvs)
+	if err != nil {
+		return err
+	}
+
+	// Command output must not mix with a machine-readable report on stdout
+	commandOutput := os.Stdout
+	if IsMachineOutput() {
+		commandOutput = os.Stderr
+	}
+
+	output := upgradeOutput{
+		Dir:       dir,
+		DryRun:    upgradeDryRun,
+		Templates: make([]templateUpgradeOutput, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		result, err := upgradeTemplateIn(cfg, dir, entry, overrides, commandOutput)
+		if err != nil {
+			return fmt.Errorf("failed to upgrade %s: %w", entry.Name, err)
+		}
+		output.Templates = append(output.Templates, result)
+	}
+
+	if IsMachineOutput() {
+		return writeOutput(output)
+	}
+	displayUpgradeResult(output)
+	return nil
+}
+
+func upgradeTemplateIn(cfg *config.Config, dir string, entry manifest.Entry, overrides map[string]any, commandOutput *os.File) (templateUpgradeOutput, error) {
+	result := templateUpgradeOutput{
+		Name:       entry.Name,
+		From:       entry.Version,
+		Migrations: []tg.MigrationResult{},
+		Files:      []tg.FileResult{},
+	}
+
+	reference := entry.Name
+	if upgradeTo != "" {
+		reference += "@" + upgradeTo
+	}
+	chain, err := resolveTemplateChain(cfg, reference)
+	if err != nil {
+		return result, err
+	}
+	tmpl := chain.Template.Config
+	result.To = tmpl.Version
+
+	if !isNewerVersion(tmpl.Version, entry.Version) {
+		result.To = entry.Version
+		result.UpToDate = true
+		return result, nil
+	}
+
+	// An upgrade that failed part way resumes after the last step that ran
+	from, skip := entry.Version, 0
+	if entry.Migration != nil {
+		from = entry.Migration.Version
+		PrintVerbose("Resuming the upgrade of %s: migrated to %s, %d step(s) of the migration to %s done\n",
+			entry.Name, versionLabel(from), entry.Migration.Steps, versionLabel(entry.Migration.To))
+	}
+
+	var migrations []tg.Migration
+	if from != "" {
+		if migrations, err = tmpl.MigrationPath(from); err != nil {
+			return result, err
+		}
+	}
+	if entry.Migration != nil && len(migrations) > 0 && migrations[0].To == entry.Migration.To {
+		skip = entry.Migration.Steps
+	}
+	PrintVerbose("%s %s -> %s: %d migration(s)\n", entry.Name, from, tmpl.Version, len(migrations))
+
+	states, err := entry.Check(dir)
+	if err != nil {
+		return result, err
+	}
+	var modified []string
+	for path, state := range states {
+		if state == manifest.FileModified {
+			modified = append(modified, path)
+		}
+	}
+	sort.Strings(modified)
+	if len(modified) > 0 && !upgradeForce && !upgradeDryRun {
+		return result, fmt.Errorf("generated files were modified by hand and would be overwritten: %s (use --force to upgrade anyway)", strings.Join(modified, ", "))
+	}
+
+	if commands := runCommands(migrations, skip); len(commands) > 0 && !upgradeAllowHooks && !upgradeDryRun {
+		return result, fmt.Errorf("its migrations run %d shell command(s): %s (review them with --dry-run and pass --allow-hooks to run them)",
+			len(commands), strings.Join(commands, "; "))
+	}
+
+	if upgradeDryRun {
+		for m, migration := range migrations {
+			for i, step := range migration.Steps {
+				if m == 0 && i < skip {
+					continue
+				}
+				op, argument := step.Op()
+				result.Migrations = append(result.Migrations, tg.MigrationResult{Version: migration.To, Op: op, Target: argument, To: step.To})
+			}
+		}
+		return result, nil
+	}
+
+	version := from
+	progress := func(migration tg.Migration, done int, values map[string]any) error {
+		state := &manifest.MigrationState{Version: version, To: migration.To, Steps: done}
+		if done == len(migration.Steps) {
+			version = migration.To
+			state = &manifest.MigrationState{Version: version}
+		}
+		entry.Migration = state
+		entry.Variables = values
+		return recordMigration(dir, entry)
+	}
+
+	steps, values, err := chain.Template.Migrate(dir, migrations, entry.Variables, skip, progress, commandOutput, os.Stderr)
+	result.Migrations = append(result.Migrations, steps...)
+	if err != nil {
+		return result, err
+	}
+
+	variables, err := chain.Template.Resolve(cfg.Defaults, values, overrides)
+	if err != nil {
+		return result, err
+	}
+
+	applied, err := chain.Template.Apply(dir, variables)
+	if err != nil {
+		return result, fmt.Errorf("failed to process template: %w", err)
+	}
+	if err := rerunGenerators(cfg, dir, chain.Template, entry.Generators, applied); err != nil {
+		return result, err
+	}
+	result.Summary = newApplyReportSummary(applied)
+	result.Files = applied.Files
+
+	return result, recordManifest(dir, chain.Template, variables, applied)
+}
+
+// rerunGenerators runs the recorded generator runs again on the new version,
+// in order, so the files they created and the snippets they injected into
+// re-rendered files come back. Their results are added to applied. A
+// generator the new version no longer has is skipped with a warning.
+func rerunGenerators(cfg *config.Config, dir string, tmpl *tg.Template, runs []manifest.GeneratorRun, applied *tg.Result) error {
+	for _, run := range runs {
+		generator, err := tmpl.Generator(run.Name)
+		if err != nil {
+			WarnColor.Fprintf(os.Stderr, "Not running generator %s again: %v\n", run.Name, err)
+			continue
+		}
+		variables, err := generator.Resolve(cfg.Defaults, run.Variables)
+		if err != nil {
+			return err
+		}
+		PrintVerbose("Running generator %s again\n", generator.Name())
+		generated, err := generator.Apply(dir, variables)
+		if err != nil {
+			return fmt.Errorf("failed to run generator %s again: %w", generator.Name(), err)
+		}
+		applied.Files = append(applied.Files, generated.Files...)
+		applied.FilesPatched += generated.FilesPatched
+	}
+	return nil
+}
+
+// runCommands lists the commands of the run steps in migrations, leaving
+// out the first skip steps of the first migration.
+func runCommands(migrations []tg.Migration, skip int) []string {
+	var commands []string
+	for m, migration := range migrations {
+		for i, step := range migration.Steps {
+			if op, command := step.Op(); op == config.OpRun && (m > 0 || i >= skip) {
+				commands = append(commands, command)
+			}
+		}
+	}
+	return commands
+}
+
+// recordMigration saves entry with how far its upgrade got, so an upgrade
+// that fails later resumes there instead of running the same steps again.
+func recordMigration(dir string, entry manifest.Entry) error {
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	m.Set(entry)
+	return m.Save(dir)
+}
+
+func displayUpgradeResult(output upgradeOutput) {
+	for _, tmpl := range output.Templates {
+		if tmpl.UpToDate {
+			SuccessColor.Printf("✓ %s is up to date", tmpl.Name)
+			if tmpl.To != "" {
+				fmt.Printf(" (v%s)", tmpl.To)
+			}
+			fmt.Println()
+			continue
+		}
+
+		InfoColor.Printf("Upgrading %s ", BoldColor.Sprint(tmpl.Name))
+		fmt.Printf("%s -> %s\n", versionLabel(tmpl.From), versionLabel(tmpl.To))
+
+		version := ""
+		for _, step := range tmpl.Migrations {
+			if step.Version != version {
+				version = step.Version
+				fmt.Printf("  Migration to v%s\n", version)
+			}
+			fmt.Printf("    %-15s %s", step.Op, step.Target)
+			if step.To != "" {
+				fmt.Printf(" -> %s", step.To)
+			}
+			if step.Skipped {
+				WarnColor.Print(" (nothing to do)")
+			}
+			if output.DryRun && step.Op == config.OpRun && !upgradeAllowHooks {
+				WarnColor.Print(" (needs --allow-hooks)")
+			}
+			fmt.Println()
+		}
+
+		if output.DryRun {
+			fmt.Println("  Dry run, nothing was changed")
+			continue
+		}
+
+		summary := tmpl.Summary
+		SuccessColor.Printf("  ✓ Upgraded to %s\n", versionLabel(tmpl.To))
+		fmt.Printf("    %d created, %d overwritten, %d patched, %d unchanged\n",
+			summary.Created+summary.Copied, summary.Overwritten,
+			summary.Injected+summary.Appended+summary.Prepended+summary.Replaced, summary.Skipped)
+	}
+}
+
+func versionLabel(version string) string {
+	if version == "" {
+		return "unversioned"
+	}
+	return "v" + version
+}
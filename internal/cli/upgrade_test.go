@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+)
+
+func TestRunCommands(t *testing.T) {
+	migrations := []tg.Migration{
+		{To: "1.1.0", Steps: []tg.MigrationStep{{Run: "make one"}, {Delete: "Makefile"}, {Run: "make two"}}},
+		{To: "2.0.0", Steps: []tg.MigrationStep{{RenameVariable: "name", To: "project"}, {Run: "go mod tidy"}}},
+	}
+
+	tests := []struct {
+		skip int
+		want []string
+	}{
+		{skip: 0, want: []string{"make one", "make two", "go mod tidy"}},
+		{skip: 1, want: []string{"make two", "go mod tidy"}},
+		{skip: 3, want: []string{"go mod tidy"}},
+	}
+	for _, tt := range tests {
+		if got := runCommands(migrations, tt.skip); !slices.Equal(got, tt.want) {
+			t.Errorf("runCommands(skip %d) = %v, want %v", tt.skip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [template-name...]",
+		Short: "Validate templates",
+		Long: `Validate checks template.toml files and parses every template file without
+generating anything.
+
+All templates in every search path are validated unless names are given.
+The command exits with a non-zero status if any template is invalid.`,
+		Example: `  # Validate every template
+  tg validate
+
+  # Validate a single template and print the result as JSON
+  tg validate hello-world --output json`,
+		RunE: runValidate,
+	}
+
+	return cmd
+}
+
+type validateOutput struct {
+	Valid     bool                     `json:"valid"`
+	Templates []templateValidateOutput `json:"templates"`
+}
+
+type templateValidateOutput struct {
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	dirs, err := validateTargets(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	output := validateOutput{
+		Valid:     true,
+		Templates: make([]templateValidateOutput, 0, len(dirs)),
+	}
+	for _, dir := range dirs {
+		result := validateTemplate(cfg, dir)
+		if !result.Valid {
+			output.Valid = false
+		}
+		output.Templates = append(output.Templates, result)
+	}
+
+	if IsMachineOutput() {
+		if err := writeOutput(output); err != nil {
+			return err
+		}
+	} else {
+		displayValidateResult(output)
+	}
+
+	if !output.Valid {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("validation failed")
+	}
+	return nil
+}
+
+func validateTargets(cfg *config.Config, names []string) ([]string, error) {
+	if len(names) > 0 {
+		dirs := make([]string, 0, len(names))
+		for _, name := range names {
+			dir, _, err := resolveTemplateDir(cfg, name)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir)
+		}
+		return dirs, nil
+	}
+
+	var dirs []string
+	for _, searchPath := range cfg.SearchPaths() {
+		found, err := templateDirsIn(searchPath)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, found...)
+	}
+	return dirs, nil
+}
+
+func validateTemplate(cfg *config.Config, dir string) templateValidateOutput {
+	result := templateValidateOutput{
+		Name:   filepath.Base(dir),
+		Path:   dir,
+		Errors: []string{},
+	}
+
+	tmpl, err := config.LoadTemplate(dir)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	result.Name = tmpl.Metadata.Name
+
+	if err := tmpl.Validate(); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	chain, err := loadTemplateChain(cfg, dir, tmpl)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	} else if err := chain.Template.Parse(); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func displayValidateResult(output validateOutput) {
+	for _, tmpl := range output.Templates {
+		if tmpl.Valid {
+			SuccessColor.Printf("✓ %s", tmpl.Name)
+			fmt.Printf(" (%s)\n", tmpl.Path)
+			continue
+		}
+
+		ErrorColor.Printf("✗ %s", tmpl.Name)
+		fmt.Printf(" (%s)\n", tmpl.Path)
+		for _, message := range tmpl.Errors {
+			fmt.Printf("    %s\n", message)
+		}
+	}
+
+	if len(output.Templates) == 0 {
+		fmt.Println("No templates found.")
+	}
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+// variableOverrides combines the values given with --var, --var-file and
+// --var-env. Naming a variable in more than one of them is an error, so a
+// secret read from a file is not silently replaced.
+func variableOverrides(values, files, envs map[string]string) (map[string]any, error) {
+	overrides := make(map[string]any, len(values)+len(files)+len(envs))
+	for name, value := range values {
+		overrides[name] = value
+	}
+
+	for name, path := range files {
+		if _, ok := overrides[name]; ok {
+			return nil, fmt.Errorf("variable '%s' is set more than once", name)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value of '%s': %w", name, err)
+		}
+		// Editors and echo end files with a newline that is not part of the value
+		value := strings.TrimSuffix(string(content), "\n")
+		overrides[name] = strings.TrimSuffix(value, "\r")
+	}
+
+	for name, env := range envs {
+		if _, ok := overrides[name]; ok {
+			return nil, fmt.Errorf("variable '%s' is set more than once", name)
+		}
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s for '%s' is not set", env, name)
+		}
+		overrides[name] = value
+	}
+	return overrides, nil
+}
+
+// printVariables lists the resolved values in verbose mode, with those of
+// secret variables redacted.
+func printVariables(variables map[string]config.Variable, values map[string]any) {
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := values[name]
+		if variables[name].Secret {
+			value = config.Redacted
+		}
+		PrintVerbose("Variable %s = %v\n", name, value)
+	}
+}
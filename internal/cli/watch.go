@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce collects the burst of events an editor save produces into
+// one render.
+const watchDebounce = 100 * time.Millisecond
+
+// templateWatch re-renders a template into a directory whenever one of its
+// files changes.
+type templateWatch struct {
+	cfg       *config.Config
+	chain     *templateChain
+	overrides map[string]any
+	outputDir string
+	// generated maps each file the last render generated to its hash, so
+	// files the template stops generating can be removed
+	generated map[string]string
+	// written holds the files the last render wrote, patched or removed and
+	// the directories above them, relative to outputDir
+	written map[string]bool
+	watcher *fsnotify.Watcher
+}
+
+// watchApply renders chain into the output directory, or a temporary
+// preview directory, and again after every change until interrupted.
+func watchApply(cfg *config.Config, chain *templateChain, overrides map[string]any) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch template: %w", err)
+	}
+	defer watcher.Close()
+
+	w := &templateWatch{
+		cfg:       cfg,
+		chain:     chain,
+		overrides: overrides,
+		outputDir: applyOutputPath,
+		generated: make(map[string]string),
+		watcher:   watcher,
+	}
+
+	if applyPreview {
+		if w.outputDir, err = os.MkdirTemp("", "tg-preview-"); err != nil {
+			return fmt.Errorf("failed to create preview directory: %w", err)
+		}
+		defer os.RemoveAll(w.outputDir)
+	} else if previous, err := manifest.Load(w.outputDir); err == nil {
+		// Files left by an earlier run are cleaned up like any other
+		if entry, ok := previous.Get(chain.Template.Name()); ok {
+			w.generated = entry.Generated()
+		}
+	}
+
+	if err := w.watchDirs(); err != nil {
+		return err
+	}
+
+	InfoColor.Printf("Watching %s, rendering into %s\n", BoldColor.Sprint(chain.Template.Name()), BoldColor.Sprint(w.outputDir))
+	fmt.Println("Press Ctrl+C to stop")
+	if len(chain.Template.Config.Hooks.PreApply) > 0 || len(chain.Template.Config.Hooks.PostApply) > 0 {
+		PrintVerbose("Hooks are not run in watch mode\n")
+	}
+	w.render()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || w.isOutput(event.Name) {
+				continue
+			}
+			PrintVerbose("%s %s\n", event.Op, event.Name)
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addTree(event.Name)
+				}
+			}
+			debounce.Reset(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			WarnColor.Printf("Watch error: %v\n", err)
+
+		case <-debounce.C:
+			w.render()
+
+		case <-interrupt:
+			fmt.Println()
+			InfoColor.Println("Stopped watching")
+			return nil
+		}
+	}
+}
+
+// isOutput reports whether path was written by the last render: the output
+// directory, one of its files or directories, the manifest or a staging
+// directory. Output inside the template directory then does not trigger a
+// render of its own, while template files inside the output directory still
+// do.
+func (w *templateWatch) isOutput(path string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(path), "/") {
+		if tg.IsStagingDir(segment) {
+			return true
+		}
+	}
+
+	output, err := filepath.Abs(w.outputDir)
+	if err != nil {
+		return false
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	relative, err := filepath.Rel(output, path)
+	if err != nil || !filepath.IsLocal(relative) && relative != "." {
+		return false
+	}
+
+	relative = filepath.ToSlash(relative)
+	return relative == "." || relative == manifest.FileName || w.written[relative]
+}
+
+// watchDirs watches every directory of every template in the chain.
+func (w *templateWatch) watchDirs() error {
+	for _, dir := range w.chain.Dirs {
+		if err := w.addTree(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+func (w *templateWatch) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+// render reloads the template and applies it. Errors are printed and the
+// previous output is left as it was.
+func (w *templateWatch) render() {
+	start := time.Now()
+	fmt.Println()
+
+	result, removed, err := w.apply()
+	if err != nil {
+		ErrorColor.Printf("✗ %s  %v\n", start.Format("15:04:05"), err)
+		if location, ok := tg.ErrorLocation(err); ok && location.Excerpt != "" {
+			fmt.Print(location.Caret())
+		}
+		return
+	}
+
+	SuccessColor.Printf("✓ %s ", start.Format("15:04:05"))
+	fmt.Printf("%d created, %d overwritten, %d unchanged, %d removed in %s\n",
+		result.Count(tg.ActionCreated)+result.Count(tg.ActionCopied), result.Count(tg.ActionOverwritten),
+		result.Count(tg.ActionSkipped), len(removed), time.Since(start).Round(time.Millisecond))
+	for _, file := range result.Files {
+		if file.Action != tg.ActionSkipped && file.Action != tg.ActionIgnored {
+			PrintVerbose("    %-11s %s\n", file.Action, file.Output)
+		}
+	}
+	for _, name := range removed {
+		PrintVerbose("    %-11s %s\n", "removed", name)
+	}
+}
+
+func (w *templateWatch) apply() (*tg.Result, []string, error) {
+	// template.toml may have changed, including what it extends
+	tmpl, err := config.LoadTemplate(w.chain.Dir())
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := loadTemplateChain(w.cfg, w.chain.Dir(), tmpl)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.chain = chain
+	if err := w.watchDirs(); err != nil {
+		return nil, nil, err
+	}
+
+	if applyJobs > 0 {
+		chain.Template.SetWorkers(applyJobs)
+	}
+	if applyStrict {
+		chain.Template.SetStrict(true)
+	}
+
+	variables, err := chain.Template.Resolve(w.cfg.Defaults, w.overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := chain.Template.Apply(w.outputDir, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generated := make(map[string]string)
+	written := make(map[string]bool)
+	for _, file := range result.Files {
+		if file.Action == tg.ActionIgnored || file.Output == "" {
+			continue
+		}
+		name := filepath.ToSlash(file.Output)
+		written[name] = true
+		// A patch of a generated file leaves its final hash
+		if _, ok := generated[name]; !file.Patch || ok {
+			generated[name] = file.Hash
+		}
+	}
+	removed := w.removeStale(written)
+	w.generated = generated
+
+	// Removed files and the directories left empty count as written too
+	w.written = make(map[string]bool)
+	for _, name := range append(slices.Collect(maps.Keys(written)), removed...) {
+		for ; name != "."; name = path.Dir(name) {
+			w.written[name] = true
+		}
+	}
+
+	if !applyPreview && !applyNoManifest {
+		if err := recordManifest(w.outputDir, chain.Template, variables, result); err != nil {
+			return nil, nil, err
+		}
+	}
+	return result, removed, nil
+}
+
+// removeStale deletes files the previous render generated and this one did
+// not write or patch, unless they were edited since, then any directories
+// left empty.
+func (w *templateWatch) removeStale(written map[string]bool) []string {
+	var removed []string
+	for name, hash := range w.generated {
+		if written[name] {
+			continue
+		}
+
+		target := filepath.Join(w.outputDir, filepath.FromSlash(name))
+		content, err := os.ReadFile(target)
+		if err != nil {
+			continue
+		}
+		if manifest.Hash(content) != hash {
+			WarnColor.Printf("  Kept %s: it was edited since it was generated\n", name)
+			continue
+		}
+		if err := os.Remove(target); err != nil {
+			WarnColor.Printf("  Failed to remove %s: %v\n", name, err)
+			continue
+		}
+		removed = append(removed, name)
+
+		for dir := filepath.Dir(target); dir != filepath.Clean(w.outputDir) && dir != "."; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/manifest"
+	"github.com/Naviary-Sanctuary/template_generator/pkg/tg"
+)
+
+func TestWatchKeepsPatchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Template{
+		Actions: []config.Action{{File: "notes.txt", Append: "added\n"}},
+	}
+	cfg.Metadata.Name = "app"
+	tmpl := tg.New(cfg, fstest.MapFS{
+		"main.txt":     {Data: []byte("main\n")},
+		"old/gone.txt": {Data: []byte("gone\n")},
+	})
+	result, err := tmpl.Apply(dir, map[string]any{})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if err := recordManifest(dir, tmpl, map[string]any{}, result); err != nil {
+		t.Fatalf("recordManifest() error = %v", err)
+	}
+
+	m, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := m.Get("app")
+	if !ok {
+		t.Fatal("manifest has no entry for app")
+	}
+	if want := []string{"notes.txt"}; !slices.Equal(entry.Patched, want) {
+		t.Errorf("Patched = %v, want %v", entry.Patched, want)
+	}
+	if _, ok := entry.Files["notes.txt"]; !ok {
+		t.Error("patched file is not recorded with its hash")
+	}
+
+	// The next render drops the action and old/gone.txt
+	w := &templateWatch{outputDir: dir, generated: entry.Generated()}
+	removed := w.removeStale(map[string]bool{"main.txt": true})
+
+	if want := []string{"old/gone.txt"}; !slices.Equal(removed, want) {
+		t.Errorf("removeStale() = %v, want %v", removed, want)
+	}
+	if _, err := os.Stat(notes); err != nil {
+		t.Errorf("patched file was removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Errorf("empty directory was kept: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Action patches a file that already exists in the output directory, after
+// the template's files are generated. Exactly one operation is set:
+//
+//	append        text added at the end of the file
+//	prepend       text added at the start of the file
+//	insert_after  Content inserted after the first line containing the marker
+//	insert_before Content inserted before that line
+//	replace       regular expression whose matches are replaced by Content
+//
+// Text and Content are rendered with the template variables. Actions are
+// idempotent: one whose result is already in the file is skipped.
+type Action struct {
+	File         string `toml:"file"`
+	Content      string `toml:"content,omitempty"`
+	Append       string `toml:"append,omitempty"`
+	Prepend      string `toml:"prepend,omitempty"`
+	InsertAfter  string `toml:"insert_after,omitempty"`
+	InsertBefore string `toml:"insert_before,omitempty"`
+	Replace      string `toml:"replace,omitempty"`
+
+	// Regex makes the insert_after and insert_before markers regular
+	// expressions matched against each line.
+	Regex bool `toml:"regex,omitempty"`
+}
+
+const (
+	OpAppend       = "append"
+	OpPrepend      = "prepend"
+	OpInsertAfter  = "insert_after"
+	OpInsertBefore = "insert_before"
+	OpReplace      = "replace"
+)
+
+// Op returns the action's operation and its argument.
+func (action Action) Op() (string, string) {
+	switch {
+	case action.Append != "":
+		return OpAppend, action.Append
+	case action.Prepend != "":
+		return OpPrepend, action.Prepend
+	case action.InsertAfter != "":
+		return OpInsertAfter, action.InsertAfter
+	case action.InsertBefore != "":
+		return OpInsertBefore, action.InsertBefore
+	case action.Replace != "":
+		return OpReplace, action.Replace
+	}
+	return "", ""
+}
+
+func (action Action) Validate() error {
+	if action.File == "" {
+		return fmt.Errorf("file is required")
+	}
+
+	count := 0
+	for _, value := range []string{action.Append, action.Prepend, action.InsertAfter, action.InsertBefore, action.Replace} {
+		if value != "" {
+			count++
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("exactly one of append, prepend, insert_after, insert_before or replace is required")
+	}
+
+	op, argument := action.Op()
+	switch op {
+	case OpAppend, OpPrepend:
+		if action.Content != "" {
+			return fmt.Errorf("%s takes the text itself; content is not used", op)
+		}
+	case OpInsertAfter, OpInsertBefore:
+		if action.Content == "" {
+			return fmt.Errorf("%s needs content", op)
+		}
+	}
+
+	if op == OpReplace || (action.Regex && (op == OpInsertAfter || op == OpInsertBefore)) {
+		if _, err := regexp.Compile(argument); err != nil {
+			return fmt.Errorf("invalid %s pattern '%s': %w", op, argument, err)
+		}
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/Naviary-Sanctuary/template_generator/internal/semver"
 	"github.com/pelletier/go-toml"
 )
 
@@ -15,8 +19,13 @@ const (
 )
 
 type Config struct {
-	TemplatesDir string         `toml:"templates_dir"`
-	Defaults     map[string]any `toml: "defaults,omitempty"`
+	TemplatesDir  string         `toml:"templates_dir"`
+	TemplatePaths []string       `toml:"template_paths,omitempty"`
+	Defaults      map[string]any `toml:"defaults,omitempty"`
+	Registry      string         `toml:"registry,omitempty"`
+
+	projectPath string
+	userPath    string
 }
 
 type Metadata struct {
@@ -26,22 +35,56 @@ type Metadata struct {
 }
 
 type Template struct {
-	Metadata  Metadata            `toml:"metadata"`
-	Variables map[string]Variable `toml:"variables"`
-	Rules     Rules               `toml:"rules"`
-	Version   string              `toml:"version,omitempty"`
+	Metadata     Metadata             `toml:"metadata"`
+	Variables    map[string]Variable  `toml:"variables"`
+	Rules        Rules                `toml:"rules"`
+	Hooks        Hooks                `toml:"hooks,omitempty"`
+	Version      string               `toml:"version,omitempty"`
+	Extends      string               `toml:"extends,omitempty"`
+	MinTGVersion string               `toml:"min_tg_version,omitempty"`
+	Generators   map[string]Generator `toml:"generators,omitempty"`
+	Actions      []Action             `toml:"actions,omitempty"`
+	Strict       bool                 `toml:"strict,omitempty"`
+	Migrations   []Migration          `toml:"migrations,omitempty"`
+	Tests        Tests                `toml:"tests,omitempty"`
 }
 
 type Variable struct {
 	Default     any    `toml:"default,omitempty"`
 	Description string `toml:"description,omitempty"`
-	Type        string `toml:"type, omitempty"`
+	Type        string `toml:"type,omitempty"`
+	Required    bool   `toml:"required,omitempty"`
+	Choices     []any  `toml:"choices,omitempty"`
+	Pattern     string `toml:"pattern,omitempty"`
+	// Order places the variable before those with a higher order; variables
+	// with the same order keep the order they are declared in
+	Order int    `toml:"order,omitempty"`
+	Group string `toml:"group,omitempty"`
+	// When is a condition such as "{{.use_db}}"; see Active
+	When string `toml:"when,omitempty"`
+	// Secret values are hidden when prompted for, redacted in verbose
+	// output and never recorded in the manifest
+	Secret bool `toml:"secret,omitempty"`
+
+	// position is where the variable is declared in template.toml
+	position int
 }
 
 type Rules struct {
 	Ignores  []string          `toml:"ignores,omitempty"`
 	Includes []string          `toml:"includes,omitempty"`
 	Renames  map[string]string `toml:"renames,omitempty"`
+
+	// Skip lists paths left out of the template entirely, such as generator
+	// file sets and test cases. It is set by tg, not read from template.toml.
+	Skip []string `toml:"-"`
+}
+
+// Hooks are shell commands run around apply. Commands are rendered with the
+// template variables before they run.
+type Hooks struct {
+	PreApply  []string `toml:"pre_apply,omitempty"`
+	PostApply []string `toml:"post_apply,omitempty"`
 }
 
 func NewConfig() *Config {
@@ -51,28 +94,144 @@ func NewConfig() *Config {
 	}
 }
 
+// Load reads the project config merged over the user config. With an empty
+// path, tg.config.toml is searched for from the working directory upwards;
+// neither file has to exist.
 func Load(path string) (*Config, error) {
-	if path == "" {
-		path = DefaultConfigFile
+	explicit := path != ""
+	if !explicit {
+		found, err := FindConfig(".")
+		if err != nil {
+			return nil, err
+		}
+		path = found
+	}
+
+	merged := &Config{Defaults: make(map[string]any)}
+
+	if userPath := UserConfigFile(); userPath != "" {
+		user, err := loadFile(userPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load user config: %w", err)
+		}
+		if user != nil {
+			merged.merge(user)
+			merged.userPath = userPath
+		}
+	}
+
+	if path != "" {
+		project, err := loadFile(path)
+		if err != nil {
+			if explicit || !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to load config file: %w", err)
+			}
+		} else {
+			merged.merge(project)
+			merged.projectPath = path
+		}
+	}
+
+	if merged.TemplatesDir == "" {
+		merged.TemplatesDir = DefaultTemplateDir
+	}
+
+	return merged, nil
+}
+
+// FindConfig looks for tg.config.toml in dir and each of its parents, the way
+// git looks for .git. It returns an empty path when there is none.
+func FindConfig(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for current, relative := absDir, dir; ; {
+		candidate := filepath.Join(current, DefaultConfigFile)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Join(relative, DefaultConfigFile), nil
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", nil
+		}
+		current, relative = parent, filepath.Join(relative, "..")
+	}
+}
+
+// UserConfigFile returns the path of the user-level config file.
+func UserConfigFile() string {
+	dir := UserConfigDir()
+	if dir == "" {
+		return ""
 	}
+	return filepath.Join(dir, "config.toml")
+}
+
+// ProjectPath returns the project config file that was loaded, if any.
+func (config *Config) ProjectPath() string {
+	return config.projectPath
+}
+
+// UserPath returns the user config file that was loaded, if any.
+func (config *Config) UserPath() string {
+	return config.userPath
+}
 
+// loadFile reads a single config file, resolving relative template paths
+// against the file's directory.
+func loadFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	var config Config
 	if err := toml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
 
-	if config.TemplatesDir == "" {
-		config.TemplatesDir = DefaultTemplateDir
+	dir := filepath.Dir(path)
+	if config.TemplatesDir != "" {
+		config.TemplatesDir = resolvePath(dir, config.TemplatesDir)
+	}
+	for i, templatePath := range config.TemplatePaths {
+		config.TemplatePaths[i] = resolvePath(dir, templatePath)
+	}
+	if config.Registry != "" && !strings.Contains(config.Registry, "://") {
+		config.Registry = resolvePath(dir, config.Registry)
 	}
 
 	return &config, nil
 }
 
+func resolvePath(base, path string) string {
+	path = ExpandPath(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// merge layers other over config: values set in other win, and defaults are
+// merged key by key.
+func (config *Config) merge(other *Config) {
+	if other.TemplatesDir != "" {
+		config.TemplatesDir = other.TemplatesDir
+	}
+	if len(other.TemplatePaths) > 0 {
+		config.TemplatePaths = other.TemplatePaths
+	}
+	if other.Registry != "" {
+		config.Registry = other.Registry
+	}
+	for key, value := range other.Defaults {
+		config.Defaults[key] = value
+	}
+}
+
 func (config *Config) Save(path string) error {
 	if path == "" {
 		path = DefaultConfigFile
@@ -91,9 +250,23 @@ func (config *Config) Save(path string) error {
 }
 
 func LoadTemplate(dir string) (*Template, error) {
-	configPath := filepath.Join(dir, TemplateConfigFile)
+	template, err := LoadTemplateFS(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	if template.Metadata.Name == "" {
+		template.Metadata.Name = filepath.Base(dir)
+	}
+	return template, nil
+}
 
-	data, err := os.ReadFile(configPath)
+// LoadTemplateFS reads template.toml from the root of fsys. Unlike
+// LoadTemplate it cannot name a template after its directory, so the
+// metadata name may be empty. A min_tg_version that is not a semantic
+// version is an error; see Validate for version.
+func LoadTemplateFS(fsys fs.FS) (*Template, error) {
+	data, err := fs.ReadFile(fsys, TemplateConfigFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read template config file: %w", err)
 	}
@@ -102,25 +275,38 @@ func LoadTemplate(dir string) (*Template, error) {
 	if err := toml.Unmarshal(data, &template); err != nil {
 		return nil, fmt.Errorf("failed to parse template config file: %w", err)
 	}
-
-	if template.Metadata.Name == "" {
-		template.Metadata.Name = filepath.Base(dir)
+	if err := template.validateMinTGVersion(); err != nil {
+		return nil, fmt.Errorf("invalid template config file: %w", err)
 	}
 
 	if template.Variables == nil {
 		template.Variables = make(map[string]Variable)
 	}
+	if err := setPositions(data, &template); err != nil {
+		return nil, fmt.Errorf("failed to parse template config file: %w", err)
+	}
 
-	for name, variable := range template.Variables {
-		if variable.Type == "" {
-			variable.Type = "string"
-			template.Variables[name] = variable
+	defaultVariableTypes(template.Variables)
+	for name, generator := range template.Generators {
+		if generator.Variables == nil {
+			generator.Variables = make(map[string]Variable)
 		}
+		defaultVariableTypes(generator.Variables)
+		template.Generators[name] = generator
 	}
 
 	return &template, nil
 }
 
+func defaultVariableTypes(variables map[string]Variable) {
+	for name, variable := range variables {
+		if variable.Type == "" {
+			variable.Type = "string"
+			variables[name] = variable
+		}
+	}
+}
+
 func SaveTemplate(dir string, tmpl *Template) error {
 	configPath := filepath.Join(dir, TemplateConfigFile)
 
@@ -141,18 +327,38 @@ func SaveTemplate(dir string, tmpl *Template) error {
 	return nil
 }
 
+// ValidateTemplateName checks that name can be used as the directory of a
+// template in the templates directory: a single path element other than
+// "." and "..".
+func ValidateTemplateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("invalid template name '%s'", name)
+	}
+	return nil
+}
+
 func (config *Config) Validate() error {
 	if config.TemplatesDir == "" {
 		return fmt.Errorf("templates_dir cannot be empty")
 	}
 
-	if info, err := os.Stat(config.TemplatesDir); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("templates directory '%s' does not exist", config.TemplatesDir)
+	found := false
+	for _, path := range config.SearchPaths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to check templates directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("'%s' is not a directory", path)
 		}
-		return fmt.Errorf("failed to check templates directory: %w", err)
-	} else if !info.IsDir() {
-		return fmt.Errorf("'%s' is not a directory", config.TemplatesDir)
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("templates directory '%s' does not exist", config.TemplatesDir)
 	}
 
 	return nil
@@ -163,11 +369,42 @@ func (t *Template) Validate() error {
 		return fmt.Errorf("template name is required")
 	}
 
+	if t.Version != "" {
+		if _, err := semver.Parse(t.Version); err != nil {
+			return fmt.Errorf("template version: %w", err)
+		}
+	}
+	if err := t.validateMinTGVersion(); err != nil {
+		return err
+	}
+
 	for name, variable := range t.Variables {
 		if err := validateVariable(name, variable); err != nil {
 			return err
 		}
 	}
+	if err := validateConditions(t.Variables); err != nil {
+		return err
+	}
+
+	for name, generator := range t.Generators {
+		if err := validateGenerator(name, generator); err != nil {
+			return err
+		}
+	}
+	for i, action := range t.Actions {
+		if err := action.Validate(); err != nil {
+			return fmt.Errorf("action #%d: %w", i+1, err)
+		}
+	}
+	for i, migration := range t.Migrations {
+		if err := migration.Validate(); err != nil {
+			return fmt.Errorf("migration #%d: %w", i+1, err)
+		}
+	}
+	if err := t.Tests.Validate(); err != nil {
+		return err
+	}
 
 	// Check for conflicting rules
 	if len(t.Rules.Includes) > 0 && len(t.Rules.Ignores) > 0 {
@@ -178,6 +415,146 @@ func (t *Template) Validate() error {
 	return nil
 }
 
+// validateMinTGVersion checks that min_tg_version is a semantic version, so
+// CheckCompatibility cannot silently pass a template it does not understand.
+func (t *Template) validateMinTGVersion() error {
+	if t.MinTGVersion != "" {
+		if _, err := semver.Parse(t.MinTGVersion); err != nil {
+			return fmt.Errorf("min_tg_version: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckCompatibility reports an error when the template needs a newer tg than
+// tgVersion. Development builds without a release version pass every check.
+func (t *Template) CheckCompatibility(tgVersion string) error {
+	if t.MinTGVersion == "" {
+		return nil
+	}
+
+	current, err := semver.Parse(tgVersion)
+	if err != nil {
+		return nil
+	}
+
+	required, err := semver.Parse(t.MinTGVersion)
+	if err != nil {
+		return fmt.Errorf("template '%s' has an invalid min_tg_version: %w", t.Metadata.Name, err)
+	}
+
+	if current.LessThan(required) {
+		return fmt.Errorf("template '%s' requires tg %s or newer, but this is tg %s", t.Metadata.Name, required, current)
+	}
+	return nil
+}
+
+// Merge returns a template that layers t over parent, as used by extends.
+// Values from t win; list rules and hooks are appended after the parent's.
+func (t *Template) Merge(parent *Template) *Template {
+	merged := *t
+	merged.Variables = mergeVariables(parent.Variables, t.Variables)
+
+	merged.Rules = Rules{
+		Ignores:  append(append([]string{}, parent.Rules.Ignores...), t.Rules.Ignores...),
+		Includes: append(append([]string{}, parent.Rules.Includes...), t.Rules.Includes...),
+		Renames:  make(map[string]string),
+	}
+	for from, to := range parent.Rules.Renames {
+		merged.Rules.Renames[from] = to
+	}
+	for from, to := range t.Rules.Renames {
+		merged.Rules.Renames[from] = to
+	}
+
+	merged.Hooks = Hooks{
+		PreApply:  append(append([]string{}, parent.Hooks.PreApply...), t.Hooks.PreApply...),
+		PostApply: append(append([]string{}, parent.Hooks.PostApply...), t.Hooks.PostApply...),
+	}
+
+	merged.Generators = make(map[string]Generator)
+	for name, generator := range parent.Generators {
+		merged.Generators[name] = generator
+	}
+	for name, generator := range t.Generators {
+		merged.Generators[name] = generator
+	}
+	merged.Actions = append(append([]Action{}, parent.Actions...), t.Actions...)
+	merged.Strict = parent.Strict || t.Strict
+	// The parent's migrations refer to its own versions, not the child's
+	merged.Migrations = t.Migrations
+
+	if merged.Metadata.Description == "" {
+		merged.Metadata.Description = parent.Metadata.Description
+	}
+	if merged.Metadata.Author == "" {
+		merged.Metadata.Author = parent.Metadata.Author
+	}
+
+	return &merged
+}
+
+// ValidateValues checks resolved variable values against the constraints
+// declared in template.toml, skipping variables whose when condition does
+// not hold.
+func (t *Template) ValidateValues(values map[string]any) error {
+	for _, variable := range t.OrderedVariables() {
+		active, err := variable.Active(values)
+		if err != nil {
+			return fmt.Errorf("variable '%s': %w", variable.Name, err)
+		}
+		if !active {
+			continue
+		}
+		if err := variable.ValidateValue(values[variable.Name]); err != nil {
+			return fmt.Errorf("variable '%s': %w", variable.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks a single value against required, choices and pattern.
+func (v Variable) ValidateValue(value any) error {
+	if value == nil || value == "" {
+		if v.Required {
+			return fmt.Errorf("value is required")
+		}
+		return nil
+	}
+
+	text := fmt.Sprint(value)
+	// Errors quote the value, which must not reveal a secret
+	shown := text
+	if v.Secret {
+		shown = Redacted
+	}
+
+	if len(v.Choices) > 0 {
+		valid := false
+		for _, choice := range v.Choices {
+			if fmt.Sprint(choice) == text {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("'%s' is not one of %v", shown, v.Choices)
+		}
+	}
+
+	if v.Pattern != "" {
+		pattern, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", v.Pattern, err)
+		}
+		if !pattern.MatchString(text) {
+			return fmt.Errorf("'%s' does not match pattern '%s'", shown, v.Pattern)
+		}
+	}
+
+	return nil
+}
+
 func validateVariable(name string, v Variable) error {
 	validTypes := []string{"string", "number", "boolean", "array"}
 	if v.Type != "" {
@@ -199,6 +576,18 @@ func validateVariable(name string, v Variable) error {
 		}
 	}
 
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("variable '%s': invalid pattern '%s': %w", name, v.Pattern, err)
+		}
+	}
+
+	if v.Default != nil && v.Default != "" {
+		if err := v.ValidateValue(v.Default); err != nil {
+			return fmt.Errorf("variable '%s': default value error: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
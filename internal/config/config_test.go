@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadTemplateFSVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{name: "semantic versions", text: "version = \"1.2.0\"\nmin_tg_version = \"0.4.0\"\n"},
+		{name: "no versions", text: "[metadata]\nname = \"web\"\n"},
+		{name: "free-form version", text: "version = \"1.0\"\n"},
+		{name: "partial min_tg_version", text: "min_tg_version = \"0.4\"\n", wantErr: "min_tg_version"},
+		{name: "constraint as min_tg_version", text: "min_tg_version = \">=0.4.0\"\n", wantErr: "min_tg_version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTemplateFS(fstest.MapFS{TemplateConfigFile: {Data: []byte(tt.text)}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadTemplateFS() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadTemplateFS() error = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	tmpl := loadTemplateText(t, "version = \"1.0\"\n[metadata]\nname = \"web\"\n")
+	if err := tmpl.Validate(); err == nil || !strings.Contains(err.Error(), "template version") {
+		t.Errorf("Validate() error = %v, want one about the template version", err)
+	}
+}
+
+func TestLoadMergesUserConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	userPath := UserConfigFile()
+	writeFile(t, userPath, `registry = "https://example.com/index.json"
+template_paths = ["~/templates"]
+[defaults]
+author = "Naviary"
+license = "MIT"
+`)
+
+	project := t.TempDir()
+	projectPath := filepath.Join(project, DefaultConfigFile)
+	writeFile(t, projectPath, `templates_dir = "tpl"
+[defaults]
+license = "Apache-2.0"
+`)
+
+	cfg, err := Load(projectPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.UserPath() != userPath || cfg.ProjectPath() != projectPath {
+		t.Errorf("paths = %q, %q, want %q, %q", cfg.UserPath(), cfg.ProjectPath(), userPath, projectPath)
+	}
+	if want := filepath.Join(project, "tpl"); cfg.TemplatesDir != want {
+		t.Errorf("TemplatesDir = %q, want %q relative to the project config", cfg.TemplatesDir, want)
+	}
+	if cfg.Registry != "https://example.com/index.json" {
+		t.Errorf("Registry = %q, want the user config's", cfg.Registry)
+	}
+	if len(cfg.TemplatePaths) != 1 || !strings.HasSuffix(cfg.TemplatePaths[0], "templates") {
+		t.Errorf("TemplatePaths = %v, want the user config's", cfg.TemplatePaths)
+	}
+	if cfg.Defaults["author"] != "Naviary" || cfg.Defaults["license"] != "Apache-2.0" {
+		t.Errorf("Defaults = %v, want author from the user config and license from the project", cfg.Defaults)
+	}
+
+	if _, err := Load(filepath.Join(project, "missing.toml")); err == nil {
+		t.Error("Load() of a missing explicit path succeeded, want an error")
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, DefaultConfigFile), "templates_dir = \"tpl\"\n")
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	found, err := FindConfig(".")
+	if err != nil {
+		t.Fatalf("FindConfig() error = %v", err)
+	}
+	if want := filepath.Join("..", "..", DefaultConfigFile); found != want {
+		t.Errorf("FindConfig() = %q, want %q", found, want)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := filepath.Join("..", "..", "tpl"); cfg.TemplatesDir != want {
+		t.Errorf("TemplatesDir = %q, want %q", cfg.TemplatesDir, want)
+	}
+
+	t.Chdir(t.TempDir())
+	if found, err := FindConfig("."); err != nil || found != "" {
+		t.Errorf("FindConfig() without a config = %q, %v, want none", found, err)
+	}
+}
+
+func writeFile(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
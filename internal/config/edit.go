@@ -0,0 +1,321 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Keys lists the settings that can be edited with tg config, besides the
+// per-variable defaults.<name> keys.
+var Keys = []string{"templates_dir", "template_paths", "registry"}
+
+const defaultsPrefix = "defaults."
+
+// ValidateKey checks that key names a known setting.
+func ValidateKey(key string) error {
+	if name, ok := strings.CutPrefix(key, defaultsPrefix); ok {
+		if name == "" || strings.Contains(name, ".") {
+			return fmt.Errorf("invalid key '%s': expected defaults.<variable>", key)
+		}
+		return nil
+	}
+
+	for _, known := range Keys {
+		if key == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key '%s' (known keys: %s, defaults.<variable>)", key, strings.Join(Keys, ", "))
+}
+
+// ParseValue interprets a command-line value as a TOML value, so `true`,
+// `8080` and `["a", "b"]` keep their types. Anything else is a string.
+func ParseValue(text string) any {
+	tree, err := toml.Load("value = " + text)
+	if err != nil {
+		return text
+	}
+	return tree.Get("value")
+}
+
+// GetValue reads key from a single config file.
+func GetValue(path, key string) (any, bool, error) {
+	tree, err := loadTree(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if !tree.Has(key) {
+		return nil, false, nil
+	}
+	return tree.Get(key), true, nil
+}
+
+// ListValues returns every setting in a single config file, keyed by its
+// dotted name.
+func ListValues(path string) (map[string]any, error) {
+	tree, err := loadTree(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+	flattenTree("", tree, values)
+	return values, nil
+}
+
+// SetValue writes key to a config file, creating the file if needed. Only
+// the key's own lines change; comments and the rest of the file are kept.
+func SetValue(path, key string, value any) error {
+	lines, tree, err := loadLines(path)
+	if err != nil {
+		return err
+	}
+
+	keyPath := splitKey(key)
+	assignment, err := assignmentLine(keyPath[len(keyPath)-1], value)
+	if err != nil {
+		return err
+	}
+
+	if start, end, ok := valueSpan(lines, tree, keyPath); ok {
+		indent := lines[start][:len(lines[start])-len(strings.TrimLeft(lines[start], " \t"))]
+		lines = slices.Replace(lines, start, end, indent+assignment)
+	} else if tree.HasPath(keyPath) {
+		// Inline tables and dotted keys are not edited in place
+		tree.SetPath(keyPath, value)
+		return saveTree(path, tree)
+	} else if inserted, ok := insertKey(lines, tree, keyPath, assignment); ok {
+		lines = inserted
+	} else {
+		tree.SetPath(keyPath, value)
+		return saveTree(path, tree)
+	}
+
+	return saveLines(path, lines)
+}
+
+// UnsetValue removes key from a config file. It reports whether the key was
+// present.
+func UnsetValue(path, key string) (bool, error) {
+	lines, tree, err := loadLines(path)
+	if err != nil {
+		return false, err
+	}
+
+	keyPath := splitKey(key)
+	if !tree.HasPath(keyPath) {
+		return false, nil
+	}
+
+	if start, end, ok := valueSpan(lines, tree, keyPath); ok {
+		return true, saveLines(path, slices.Delete(lines, start, end))
+	}
+
+	if err := tree.DeletePath(keyPath); err != nil {
+		return false, fmt.Errorf("failed to remove '%s': %w", key, err)
+	}
+	return true, saveTree(path, tree)
+}
+
+func splitKey(key string) []string {
+	if name, ok := strings.CutPrefix(key, defaultsPrefix); ok {
+		return []string{"defaults", name}
+	}
+	return []string{key}
+}
+
+// assignmentLine renders name = value, quoting name if TOML needs it to.
+func assignmentLine(name string, value any) (string, error) {
+	tree, err := toml.TreeFromMap(map[string]any{name: value})
+	if err != nil {
+		return "", fmt.Errorf("invalid value for '%s': %w", name, err)
+	}
+	data, err := tree.Marshal()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// valueSpan finds the lines [start, end) holding the assignment of keyPath,
+// which may continue over several lines for an array. It fails for a key
+// inside an inline table or set with a dotted key.
+func valueSpan(lines []string, tree *toml.Tree, keyPath []string) (int, int, bool) {
+	if !tree.HasPath(keyPath) {
+		return 0, 0, false
+	}
+	if _, ok := tree.GetPath(keyPath).(*toml.Tree); ok {
+		return 0, 0, false
+	}
+	position := tree.GetPositionPath(keyPath)
+	if position.Invalid() || position.Line > len(lines) {
+		return 0, 0, false
+	}
+
+	name := keyPath[len(keyPath)-1]
+	start := position.Line - 1
+	for end := start + 1; end <= len(lines); end++ {
+		snippet, err := toml.Load(strings.Join(lines[start:end], "\n"))
+		if err != nil {
+			continue
+		}
+		keys := snippet.Keys()
+		if len(keys) != 1 || keys[0] != name {
+			return 0, 0, false
+		}
+		return start, end, true
+	}
+	return 0, 0, false
+}
+
+// insertKey adds the assignment of a key that is not set yet: a top-level
+// key after the last top-level key, or before the first table, and a
+// default at the end of the [defaults] table, which is added if missing.
+func insertKey(lines []string, tree *toml.Tree, keyPath []string, assignment string) ([]string, bool) {
+	if len(keyPath) == 1 {
+		last, firstTable := -1, len(lines)
+		for _, key := range tree.Keys() {
+			line := tree.GetPosition(key).Line - 1
+			if isTable(tree.Get(key)) {
+				firstTable = min(firstTable, line)
+			} else if _, end, ok := valueSpan(lines, tree, []string{key}); ok {
+				last = max(last, end)
+			}
+		}
+
+		if last >= 0 {
+			return slices.Insert(lines, last, assignment), true
+		}
+		if firstTable == len(lines) {
+			return appendBlock(lines, assignment), true
+		}
+		// Keep a table's comment next to its header
+		at := firstTable
+		for at > 0 && strings.HasPrefix(strings.TrimSpace(lines[at-1]), "#") {
+			at--
+		}
+		return slices.Insert(lines, at, assignment, ""), true
+	}
+
+	table := keyPath[0]
+	if !tree.Has(table) {
+		return appendBlock(lines, "["+table+"]", assignment), true
+	}
+	subtree, ok := tree.Get(table).(*toml.Tree)
+	if !ok {
+		return nil, false
+	}
+	header := tree.GetPosition(table).Line - 1
+	if header < 0 || header >= len(lines) || strings.TrimSpace(lines[header]) != "["+table+"]" {
+		return nil, false
+	}
+
+	at := header + 1
+	for _, key := range subtree.Keys() {
+		_, end, ok := valueSpan(lines, tree, []string{table, key})
+		if !ok {
+			return nil, false
+		}
+		at = max(at, end)
+	}
+	return slices.Insert(lines, at, assignment), true
+}
+
+func isTable(value any) bool {
+	switch value.(type) {
+	case *toml.Tree, []*toml.Tree:
+		return true
+	}
+	return false
+}
+
+// appendBlock adds block at the end of the file, after a blank line.
+func appendBlock(lines []string, block ...string) []string {
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+		lines = append(lines, "")
+	}
+	return append(lines, block...)
+}
+
+// SortedKeys returns the keys of values in a stable order.
+func SortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func loadTree(path string) (*toml.Tree, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return toml.TreeFromMap(map[string]any{})
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return tree, nil
+}
+
+// loadLines reads a config file as lines, along with its parsed tree. A
+// missing file has no lines.
+func loadLines(path string) ([]string, *toml.Tree, error) {
+	tree, err := loadTree(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, tree, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, tree, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), tree, nil
+}
+
+func saveTree(path string, tree *toml.Tree) error {
+	data, err := tree.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return writeConfig(path, data)
+}
+
+func saveLines(path string, lines []string) error {
+	return writeConfig(path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+func writeConfig(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+func flattenTree(prefix string, tree *toml.Tree, values map[string]any) {
+	for _, key := range tree.Keys() {
+		value := tree.Get(key)
+		name := prefix + key
+		if subtree, ok := value.(*toml.Tree); ok {
+			flattenTree(name+".", subtree, values)
+			continue
+		}
+		values[name] = value
+	}
+}
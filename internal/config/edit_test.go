@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const commentedConfig = `# Template Generator Configuration
+
+# Directory containing templates
+templates_dir = ".tg"
+
+# Search paths
+template_paths = [
+  ".tg",
+  "shared",
+]
+
+# Default variables for all templates
+[defaults]
+author = "Naviary" # who wrote it
+`
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		key   string
+		value any
+		want  string
+	}{
+		{
+			name:  "replace a key",
+			text:  commentedConfig,
+			key:   "templates_dir",
+			value: "templates",
+			want: `# Template Generator Configuration
+
+# Directory containing templates
+templates_dir = "templates"
+
+# Search paths
+template_paths = [
+  ".tg",
+  "shared",
+]
+
+# Default variables for all templates
+[defaults]
+author = "Naviary" # who wrote it
+`,
+		},
+		{
+			name:  "replace a multi-line array",
+			text:  commentedConfig,
+			key:   "template_paths",
+			value: []any{"a"},
+			want: `# Template Generator Configuration
+
+# Directory containing templates
+templates_dir = ".tg"
+
+# Search paths
+template_paths = ["a"]
+
+# Default variables for all templates
+[defaults]
+author = "Naviary" # who wrote it
+`,
+		},
+		{
+			name:  "add a key after the last top-level key",
+			text:  commentedConfig,
+			key:   "registry",
+			value: "https://example.com/index.json",
+			want: `# Template Generator Configuration
+
+# Directory containing templates
+templates_dir = ".tg"
+
+# Search paths
+template_paths = [
+  ".tg",
+  "shared",
+]
+registry = "https://example.com/index.json"
+
+# Default variables for all templates
+[defaults]
+author = "Naviary" # who wrote it
+`,
+		},
+		{
+			name:  "add a key before the first table",
+			text:  "# Defaults\n[defaults]\nauthor = \"Naviary\"\n",
+			key:   "templates_dir",
+			value: "templates",
+			want:  "templates_dir = \"templates\"\n\n# Defaults\n[defaults]\nauthor = \"Naviary\"\n",
+		},
+		{
+			name:  "add a default",
+			text:  commentedConfig,
+			key:   "defaults.port",
+			value: int64(8080),
+			want:  commentedConfig + "port = 8080\n",
+		},
+		{
+			name:  "replace a default",
+			text:  commentedConfig,
+			key:   "defaults.author",
+			value: "Someone",
+			want:  commentedConfig[:len(commentedConfig)-len("author = \"Naviary\" # who wrote it\n")] + "author = \"Someone\"\n",
+		},
+		{
+			name:  "add the defaults table",
+			text:  "# Directory containing templates\ntemplates_dir = \".tg\"\n",
+			key:   "defaults.use db",
+			value: true,
+			want:  "# Directory containing templates\ntemplates_dir = \".tg\"\n\n[defaults]\n\"use db\" = true\n",
+		},
+		{
+			name:  "create the file",
+			key:   "registry",
+			value: "./registry",
+			want:  "registry = \"./registry\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultConfigFile)
+			if tt.text != "" {
+				if err := os.WriteFile(path, []byte(tt.text), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := SetValue(path, tt.key, tt.value); err != nil {
+				t.Fatalf("SetValue() error = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("SetValue() wrote\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValueInlineTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte("defaults = {author = \"Naviary\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetValue(path, "defaults.author", "Someone"); err != nil {
+		t.Fatalf("SetValue() error = %v", err)
+	}
+	value, ok, err := GetValue(path, "defaults.author")
+	if err != nil || !ok || value != "Someone" {
+		t.Errorf("GetValue() = %v, %v, %v, want Someone", value, ok, err)
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte(commentedConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := UnsetValue(path, "template_paths")
+	if err != nil || !removed {
+		t.Fatalf("UnsetValue() = %v, %v, want true", removed, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Template Generator Configuration
+
+# Directory containing templates
+templates_dir = ".tg"
+
+# Search paths
+
+# Default variables for all templates
+[defaults]
+author = "Naviary" # who wrote it
+`
+	if got := string(data); got != want {
+		t.Errorf("UnsetValue() wrote\n%s\nwant\n%s", got, want)
+	}
+
+	if removed, err := UnsetValue(path, "registry"); err != nil || removed {
+		t.Errorf("UnsetValue(missing) = %v, %v, want false", removed, err)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GeneratorsDir holds generator file sets by default, one directory per
+// generator.
+const GeneratorsDir = "generators"
+
+// Generator is a named, smaller template inside a template, used to add
+// files to an existing project, e.g. one handler at a time.
+type Generator struct {
+	Description string              `toml:"description,omitempty"`
+	Files       string              `toml:"files,omitempty"`
+	Variables   map[string]Variable `toml:"variables,omitempty"`
+	Actions     []Action            `toml:"actions,omitempty"`
+}
+
+// FilesDir returns the generator's directory inside the template.
+func (g Generator) FilesDir(name string) string {
+	if g.Files != "" {
+		return path.Clean(g.Files)
+	}
+	return path.Join(GeneratorsDir, name)
+}
+
+// GeneratorNames returns the names of the template's generators, sorted.
+func (t *Template) GeneratorNames() []string {
+	names := make([]string, 0, len(t.Generators))
+	for name := range t.Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GeneratorDirs returns the directories of every generator, which are not
+// part of the template's own files.
+func (t *Template) GeneratorDirs() []string {
+	var dirs []string
+	for _, name := range t.GeneratorNames() {
+		dirs = append(dirs, t.Generators[name].FilesDir(name))
+	}
+	return dirs
+}
+
+// Generator returns the named generator as a template of its own.
+func (t *Template) Generator(name string) (*Template, error) {
+	generator, ok := t.Generators[name]
+	if !ok {
+		available := t.GeneratorNames()
+		if len(available) == 0 {
+			return nil, fmt.Errorf("template '%s' has no generators", t.Metadata.Name)
+		}
+		return nil, fmt.Errorf("template '%s' has no generator '%s' (available: %s)", t.Metadata.Name, name, strings.Join(available, ", "))
+	}
+
+	variables := generator.Variables
+	if variables == nil {
+		variables = make(map[string]Variable)
+	}
+
+	return &Template{
+		Metadata: Metadata{
+			Name:        t.Metadata.Name + ":" + name,
+			Description: generator.Description,
+			Author:      t.Metadata.Author,
+		},
+		Variables:    variables,
+		Version:      t.Version,
+		MinTGVersion: t.MinTGVersion,
+		Actions:      generator.Actions,
+		Strict:       t.Strict,
+	}, nil
+}
+
+func validateGenerator(name string, generator Generator) error {
+	if name == "" || strings.ContainsAny(name, ":/ ") {
+		return fmt.Errorf("invalid generator name '%s'", name)
+	}
+
+	for variableName, variable := range generator.Variables {
+		if err := validateVariable(variableName, variable); err != nil {
+			return fmt.Errorf("generator '%s': %w", name, err)
+		}
+	}
+	if err := validateConditions(generator.Variables); err != nil {
+		return fmt.Errorf("generator '%s': %w", name, err)
+	}
+
+	for i, action := range generator.Actions {
+		if err := action.Validate(); err != nil {
+			return fmt.Errorf("generator '%s': action #%d: %w", name, i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/semver"
+)
+
+// Migration upgrades a project generated from a version matching From to
+// version To. Its steps run in order in the project directory before the
+// new version is rendered:
+//
+//	rename_path      move a generated file or directory to To
+//	rename_variable  rename a recorded variable to To
+//	delete           remove a file or directory that is no longer generated
+//	run              shell command, like a hook
+//
+// Paths and commands are rendered with the project's variables.
+type Migration struct {
+	From  string          `toml:"from"`
+	To    string          `toml:"to"`
+	Steps []MigrationStep `toml:"steps"`
+}
+
+type MigrationStep struct {
+	RenamePath     string `toml:"rename_path,omitempty"`
+	RenameVariable string `toml:"rename_variable,omitempty"`
+	Delete         string `toml:"delete,omitempty"`
+	Run            string `toml:"run,omitempty"`
+	To             string `toml:"to,omitempty"`
+}
+
+const (
+	OpRenamePath     = "rename_path"
+	OpRenameVariable = "rename_variable"
+	OpDelete         = "delete"
+	OpRun            = "run"
+)
+
+// Op returns the step's operation and its argument.
+func (step MigrationStep) Op() (string, string) {
+	switch {
+	case step.RenamePath != "":
+		return OpRenamePath, step.RenamePath
+	case step.RenameVariable != "":
+		return OpRenameVariable, step.RenameVariable
+	case step.Delete != "":
+		return OpDelete, step.Delete
+	case step.Run != "":
+		return OpRun, step.Run
+	}
+	return "", ""
+}
+
+func (step MigrationStep) Validate() error {
+	count := 0
+	for _, value := range []string{step.RenamePath, step.RenameVariable, step.Delete, step.Run} {
+		if value != "" {
+			count++
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("exactly one of rename_path, rename_variable, delete or run is required")
+	}
+
+	switch op, _ := step.Op(); op {
+	case OpRenamePath, OpRenameVariable:
+		if step.To == "" {
+			return fmt.Errorf("%s needs to", op)
+		}
+	default:
+		if step.To != "" {
+			return fmt.Errorf("%s does not take to", op)
+		}
+	}
+	return nil
+}
+
+func (migration Migration) Validate() error {
+	if migration.From == "" {
+		return fmt.Errorf("from is required")
+	}
+	if _, err := semver.ParseConstraint(migration.From); err != nil {
+		return fmt.Errorf("from: %w", err)
+	}
+	if _, err := semver.Parse(migration.To); err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+
+	for i, step := range migration.Steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step #%d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// MigrationPath returns the migrations that take a project generated at
+// version from up to the template's own version, in the order they run.
+// At each version the first declared migration whose from range matches
+// and whose to version is newer is taken.
+func (t *Template) MigrationPath(from string) ([]Migration, error) {
+	target, err := semver.Parse(t.Version)
+	if err != nil {
+		return nil, fmt.Errorf("template '%s' has no valid version to upgrade to", t.Metadata.Name)
+	}
+
+	current, err := semver.Parse(from)
+	if err != nil {
+		return nil, fmt.Errorf("recorded version '%s' is not a valid version", from)
+	}
+
+	var path []Migration
+	for current.LessThan(target) {
+		next, ok, err := t.nextMigration(current, target)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		path = append(path, next)
+		current = semver.MustParse(next.To)
+	}
+	return path, nil
+}
+
+func (t *Template) nextMigration(current, target semver.Version) (Migration, bool, error) {
+	for i, migration := range t.Migrations {
+		constraint, err := semver.ParseConstraint(migration.From)
+		if err != nil {
+			return Migration{}, false, fmt.Errorf("migration #%d: %w", i+1, err)
+		}
+		to, err := semver.Parse(migration.To)
+		if err != nil {
+			return Migration{}, false, fmt.Errorf("migration #%d: to: %w", i+1, err)
+		}
+
+		if constraint.Check(current) && current.LessThan(to) && !target.LessThan(to) {
+			return migration, true, nil
+		}
+	}
+	return Migration{}, false, nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const userConfigDirName = "tg"
+
+// UserConfigDir returns the per-user tg directory, $XDG_CONFIG_HOME/tg or
+// ~/.config/tg when XDG_CONFIG_HOME is unset.
+func UserConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, userConfigDirName)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", userConfigDirName)
+}
+
+// UserTemplatesDir returns the user-global templates directory, which is
+// always searched after the configured paths.
+func UserTemplatesDir() string {
+	dir := UserConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "templates")
+}
+
+// SearchPaths returns the directories searched for templates, in lookup
+// order. template_paths replaces templates_dir when set, and the user-global
+// templates directory is always searched last.
+func (config *Config) SearchPaths() []string {
+	configured := config.TemplatePaths
+	if len(configured) == 0 {
+		configured = []string{config.TemplatesDir}
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		key := filepath.Clean(path)
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		paths = append(paths, path)
+	}
+
+	for _, path := range configured {
+		add(ExpandPath(path))
+	}
+	add(UserTemplatesDir())
+
+	return paths
+}
+
+// ExpandPath expands a leading ~ and environment variables in path.
+func ExpandPath(path string) string {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Tests opts a template into golden tests. Dir holds the test cases:
+// <dir>/<case>.toml with the variables to apply, <dir>/<case>/expected/
+// with the files they should produce and, optionally, <dir>/<case>/input/
+// with the files already in the output directory. Dir is not part of the
+// template's output.
+type Tests struct {
+	Dir string `toml:"dir,omitempty"`
+}
+
+// Validate checks that Dir is a directory inside the template.
+func (t Tests) Validate() error {
+	if t.Dir == "" {
+		return nil
+	}
+	if dir := path.Clean(t.Dir); dir == "." || !fs.ValidPath(dir) {
+		return fmt.Errorf("tests dir '%s' must be a relative path inside the template", t.Dir)
+	}
+	return nil
+}
+
+type TestCase struct {
+	Name        string         `toml:"-"`
+	Description string         `toml:"description,omitempty"`
+	Variables   map[string]any `toml:"variables"`
+
+	dir string
+}
+
+// ExpectedDir is where the case's golden files live, relative to the
+// template root.
+func (c TestCase) ExpectedDir() string {
+	return path.Join(c.dir, c.Name, "expected")
+}
+
+// InputDir holds files the output directory starts with, for templates
+// whose actions patch existing files.
+func (c TestCase) InputDir() string {
+	return path.Join(c.dir, c.Name, "input")
+}
+
+// TestPaths returns the tests directory, which is left out of the
+// template's output. A template without [tests] has none.
+func (t *Template) TestPaths() []string {
+	if t.Tests.Dir == "" {
+		return nil
+	}
+	return []string{path.Clean(t.Tests.Dir)}
+}
+
+// LoadTestCases reads every <dir>/*.toml of the template at the root of
+// fsys, sorted by name. Each must be a test case; a file that is not one is
+// an error rather than being skipped. A template without [tests] has no
+// cases.
+func LoadTestCases(fsys fs.FS, tests Tests) ([]TestCase, error) {
+	if tests.Dir == "" {
+		return nil, nil
+	}
+	dir := path.Clean(tests.Dir)
+
+	files, err := fs.Glob(fsys, path.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	cases := make([]TestCase, 0, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read test case %s: %w", file, err)
+		}
+
+		var testCase TestCase
+		if err := toml.Unmarshal(data, &testCase); err != nil {
+			return nil, fmt.Errorf("failed to parse test case %s: %w", file, err)
+		}
+		if err := checkTestCaseKeys(data); err != nil {
+			return nil, fmt.Errorf("test case %s: %w", file, err)
+		}
+		testCase.Name = strings.TrimSuffix(path.Base(file), ".toml")
+		testCase.dir = dir
+		cases = append(cases, testCase)
+	}
+	return cases, nil
+}
+
+// checkTestCaseKeys refuses keys a test case does not have, so a file that
+// is something else fails instead of running as a case with no variables.
+func checkTestCaseKeys(data []byte) error {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return err
+	}
+	for _, key := range tree.Keys() {
+		if key != "description" && key != "variables" {
+			return fmt.Errorf("unknown key '%s' (a test case has description and [variables])", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTestPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		tests Tests
+		want  []string
+	}{
+		{name: "no tests", want: nil},
+		{name: "tests dir", tests: Tests{Dir: "tests"}, want: []string{"tests"}},
+		{name: "cleaned", tests: Tests{Dir: "./golden/"}, want: []string{"golden"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &Template{Tests: tt.tests}
+			if got := tmpl.TestPaths(); !slices.Equal(got, tt.want) {
+				t.Errorf("TestPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTestCases(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tests/basic.toml":              {Data: []byte("description = \"Basic\"\n[variables]\nname = \"shop\"\n")},
+		"tests/basic/expected/main.go":  {Data: []byte("package main\n")},
+		"tests/other.toml":              {Data: []byte("[variables]\n")},
+		"tests/other/input/existing.go": {Data: []byte("")},
+		"golden/fixtures.toml":          {Data: []byte("[[fixture]]\nname = \"a\"\n")},
+		"broken/case.toml":              {Data: []byte("variables = [\n")},
+	}
+
+	tests := []struct {
+		name      string
+		tests     Tests
+		wantNames []string
+		wantErr   string
+	}{
+		{name: "not opted in", wantNames: nil},
+		{name: "cases", tests: Tests{Dir: "tests"}, wantNames: []string{"basic", "other"}},
+		{name: "missing dir", tests: Tests{Dir: "missing"}, wantNames: []string{}},
+		{name: "not a test case", tests: Tests{Dir: "golden"}, wantErr: "unknown key 'fixture'"},
+		{name: "invalid toml", tests: Tests{Dir: "broken"}, wantErr: "failed to parse test case broken/case.toml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cases, err := LoadTestCases(fsys, tt.tests)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadTestCases() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTestCases() error = %v", err)
+			}
+
+			var names []string
+			if cases != nil {
+				names = []string{}
+			}
+			for _, testCase := range cases {
+				names = append(names, testCase.Name)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("LoadTestCases() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+
+	cases, err := LoadTestCases(fsys, Tests{Dir: "tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cases[0].ExpectedDir(); got != "tests/basic/expected" {
+		t.Errorf("ExpectedDir() = %q", got)
+	}
+	if got := cases[1].InputDir(); got != "tests/other/input" {
+		t.Errorf("InputDir() = %q", got)
+	}
+}
+
+func TestValidateTests(t *testing.T) {
+	for _, dir := range []string{"", "tests", "spec/golden"} {
+		if err := (Tests{Dir: dir}).Validate(); err != nil {
+			t.Errorf("Validate(%q) error = %v", dir, err)
+		}
+	}
+	for _, dir := range []string{".", "../tests", "/abs"} {
+		if err := (Tests{Dir: dir}).Validate(); err == nil {
+			t.Errorf("Validate(%q) succeeded, want an error", dir)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+	"github.com/pelletier/go-toml"
+)
+
+// NamedVariable is a variable together with its name, as listed by
+// OrderedVariables.
+type NamedVariable struct {
+	Name string
+	Variable
+}
+
+// OrderedVariables returns variables sorted by their order field, then the
+// order they are declared in template.toml, then name, with the variables
+// of each group moved together: ungrouped variables first, then each group
+// where its first variable is. Prompts, tg info and validation all list
+// variables in this order.
+func OrderedVariables(variables map[string]Variable) []NamedVariable {
+	ordered := make([]NamedVariable, 0, len(variables))
+	for name, variable := range variables {
+		ordered = append(ordered, NamedVariable{Name: name, Variable: variable})
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		if a.position != b.position {
+			return a.position < b.position
+		}
+		return a.Name < b.Name
+	})
+
+	groups := map[string]int{"": 0}
+	for _, variable := range ordered {
+		if _, ok := groups[variable.Group]; !ok {
+			groups[variable.Group] = len(groups)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return groups[ordered[i].Group] < groups[ordered[j].Group]
+	})
+	return ordered
+}
+
+// OrderedVariables returns the template's variables in declaration order.
+func (t *Template) OrderedVariables() []NamedVariable {
+	return OrderedVariables(t.Variables)
+}
+
+// Active reports whether the variable applies to values: it has no when
+// condition, or the condition renders to something other than "", "false",
+// "0" or "<no value>". Inactive variables are not asked for and their
+// constraints are not checked.
+func (v Variable) Active(values map[string]any) (bool, error) {
+	if v.When == "" {
+		return true, nil
+	}
+
+	tmpl, err := expr.Parse("when", v.When)
+	if err != nil {
+		return false, fmt.Errorf("invalid when '%s': %w", v.When, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		return false, fmt.Errorf("failed to evaluate when '%s': %w", v.When, err)
+	}
+	return expr.Truthy(rendered.String()), nil
+}
+
+// Redacted is shown in place of a secret variable's value.
+const Redacted = "********"
+
+// WithoutSecrets returns a copy of values without those of the secret
+// variables.
+func WithoutSecrets(variables map[string]Variable, values map[string]any) map[string]any {
+	public := make(map[string]any, len(values))
+	for name, value := range values {
+		if !variables[name].Secret {
+			public[name] = value
+		}
+	}
+	return public
+}
+
+// validateConditions checks that each when condition parses and only
+// refers to variables that come before it, so they are known by the time
+// it is evaluated.
+func validateConditions(variables map[string]Variable) error {
+	var earlier []string
+	for _, variable := range OrderedVariables(variables) {
+		if variable.When != "" {
+			tmpl, err := expr.Parse("when", variable.When)
+			if err != nil {
+				return fmt.Errorf("variable '%s': invalid when '%s': %w", variable.Name, variable.When, err)
+			}
+			for _, name := range expr.Fields(tmpl.Tree.Root) {
+				if _, ok := variables[name]; !ok {
+					return fmt.Errorf("variable '%s': when refers to undeclared variable '%s'", variable.Name, name)
+				}
+				if !slices.Contains(earlier, name) {
+					return fmt.Errorf("variable '%s': when refers to '%s', which must come before it", variable.Name, name)
+				}
+			}
+		}
+		earlier = append(earlier, variable.Name)
+	}
+	return nil
+}
+
+// setPositions records where each variable is declared in the template.toml
+// data, for OrderedVariables.
+func setPositions(data []byte, template *Template) error {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return err
+	}
+	inline := inlinePositions(data)
+
+	position := func(path ...string) int {
+		p := tree.GetPositionPath(path)
+		if p.Invalid() {
+			// go-toml does not record where inline tables are declared
+			table := strings.Join(path[:len(path)-1], ".")
+			return inline[table][path[len(path)-1]]
+		}
+		return p.Line<<16 | p.Col
+	}
+
+	for name, variable := range template.Variables {
+		variable.position = position("variables", name)
+		template.Variables[name] = variable
+	}
+	for generatorName, generator := range template.Generators {
+		for name, variable := range generator.Variables {
+			variable.position = position("generators", generatorName, "variables", name)
+			generator.Variables[name] = variable
+		}
+	}
+	return nil
+}
+
+// inlinePositions finds keys assigned at the start of a line, such as
+// `name = {default = "x"}`, keyed by the dotted name of the table header
+// above them. Keys under an array of tables and lines inside multi-line
+// strings are left out.
+func inlinePositions(data []byte) map[string]map[string]int {
+	positions := make(map[string]map[string]int)
+	table, inArray := "", false
+	multiline := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if multiline != "" {
+			if strings.Count(line, multiline)%2 == 1 {
+				multiline = ""
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			inArray = true
+			continue
+		case strings.HasPrefix(line, "["):
+			if end := strings.IndexByte(line, ']'); end > 0 {
+				table, inArray = tableName(line[1:end]), false
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		for _, quotes := range []string{`"""`, `'''`} {
+			if strings.Count(value, quotes)%2 == 1 {
+				multiline = quotes
+			}
+		}
+		if inArray {
+			continue
+		}
+
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		if positions[table] == nil {
+			positions[table] = make(map[string]int)
+		}
+		positions[table][key] = (i + 1) << 16
+	}
+	return positions
+}
+
+// tableName turns a table header such as ` generators."my-gen".variables `
+// into generators.my-gen.variables.
+func tableName(header string) string {
+	segments := strings.Split(header, ".")
+	for i, segment := range segments {
+		segments[i] = strings.Trim(strings.TrimSpace(segment), `"'`)
+	}
+	return strings.Join(segments, ".")
+}
+
+// mergeVariables layers child over parent. Parent variables keep their
+// place, even when the child redefines them, and the child's new
+// variables follow.
+func mergeVariables(parent, child map[string]Variable) map[string]Variable {
+	merged := make(map[string]Variable, len(parent)+len(child))
+
+	next := 0
+	for _, variable := range OrderedVariables(parent) {
+		variable.position = next
+		merged[variable.Name] = variable.Variable
+		next++
+	}
+	for _, variable := range OrderedVariables(child) {
+		if existing, ok := merged[variable.Name]; ok {
+			variable.position = existing.position
+		} else {
+			variable.position = next
+			next++
+		}
+		merged[variable.Name] = variable.Variable
+	}
+	return merged
+}
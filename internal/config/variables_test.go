@@ -0,0 +1,263 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func loadTemplateText(t *testing.T, text string) *Template {
+	t.Helper()
+	tmpl, err := LoadTemplateFS(fstest.MapFS{TemplateConfigFile: {Data: []byte(text)}})
+	if err != nil {
+		t.Fatalf("LoadTemplateFS() error = %v", err)
+	}
+	return tmpl
+}
+
+func variableNames(variables []NamedVariable) []string {
+	names := make([]string, len(variables))
+	for i, variable := range variables {
+		names[i] = variable.Name
+	}
+	return names
+}
+
+func TestOrderedVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "inline tables in declaration order",
+			text: `
+[variables]
+zeta = {default = "z"}
+alpha = {default = "a"}
+mid = {default = "m"}
+`,
+			want: []string{"zeta", "alpha", "mid"},
+		},
+		{
+			name: "sub-tables and inline tables mixed",
+			text: `
+[variables]
+b = {default = "b"}
+
+[variables.a]
+default = "a"
+
+[variables.c]
+default = "c"
+`,
+			want: []string{"b", "a", "c"},
+		},
+		{
+			name: "order field first",
+			text: `
+[variables]
+first = {default = "1"}
+second = {default = "2", order = -1}
+third = {default = "3"}
+`,
+			want: []string{"second", "first", "third"},
+		},
+		{
+			name: "ungrouped variables come first",
+			text: `
+[variables]
+host = {group = "Server"}
+name = {}
+port = {group = "Server"}
+license = {}
+`,
+			want: []string{"name", "license", "host", "port"},
+		},
+		{
+			name: "interleaved groups are kept together",
+			text: `
+[variables]
+host = {group = "Server"}
+use_db = {group = "Database"}
+port = {group = "Server"}
+db_driver = {group = "Database"}
+`,
+			want: []string{"host", "port", "use_db", "db_driver"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := loadTemplateText(t, tt.text)
+			if got := variableNames(tmpl.OrderedVariables()); !slices.Equal(got, tt.want) {
+				t.Errorf("OrderedVariables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratorVariablesOrder(t *testing.T) {
+	tmpl := loadTemplateText(t, `
+[generators."my-handler".variables]
+name = {}
+file = {}
+package = {}
+`)
+	generator, err := tmpl.Generator("my-handler")
+	if err != nil {
+		t.Fatalf("Generator() error = %v", err)
+	}
+	want := []string{"name", "file", "package"}
+	if got := variableNames(generator.OrderedVariables()); !slices.Equal(got, want) {
+		t.Errorf("OrderedVariables() = %v, want %v", got, want)
+	}
+}
+
+func TestInlinePositions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]map[string]int
+	}{
+		{
+			name: "keys under their table",
+			text: "name = \"x\"\n[variables]\na = {}\n  b = {}\n",
+			want: map[string]map[string]int{
+				"":          {"name": 1 << 16},
+				"variables": {"a": 3 << 16, "b": 4 << 16},
+			},
+		},
+		{
+			name: "quoted keys and headers",
+			text: "[ generators . \"my-gen\" . variables ] # comment\n\"my var\" = {}\n'other' = {}\n",
+			want: map[string]map[string]int{
+				"generators.my-gen.variables": {"my var": 2 << 16, "other": 3 << 16},
+			},
+		},
+		{
+			name: "arrays of tables",
+			text: "[variables]\na = {}\n[[migrations]]\nfrom = \"1.*\"\n[variables2]\nb = {}\n",
+			want: map[string]map[string]int{
+				"variables":  {"a": 2 << 16},
+				"variables2": {"b": 6 << 16},
+			},
+		},
+		{
+			name: "multi-line strings",
+			text: "[variables]\na = {}\ndescription = \"\"\"\n[not.a.table]\nb = 1\n\"\"\"\nc = {}\nd = '''one line'''\ne = {}\n",
+			want: map[string]map[string]int{
+				"variables": {"a": 2 << 16, "description": 3 << 16, "c": 7 << 16, "d": 8 << 16, "e": 9 << 16},
+			},
+		},
+		{
+			name: "comments",
+			text: "[variables]\n# a = {}\nb = {} # c = {}\n",
+			want: map[string]map[string]int{
+				"variables": {"b": 3 << 16},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inlinePositions([]byte(tt.text))
+			if !maps.EqualFunc(got, tt.want, maps.Equal) {
+				t.Errorf("inlinePositions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	tmpl := loadTemplateText(t, `
+[variables]
+module = {required = true, pattern = "^[a-z][a-z0-9_]*$"}
+license = {default = "MIT", choices = ["MIT", "Apache-2.0"]}
+token = {secret = true, pattern = "^tok_"}
+use_db = {type = "boolean", default = false}
+db_driver = {required = true, when = "{{.use_db}}"}
+`)
+
+	tests := []struct {
+		name    string
+		values  map[string]any
+		wantErr string
+	}{
+		{name: "valid", values: map[string]any{"module": "shop", "license": "MIT"}},
+		{name: "missing required", values: map[string]any{"license": "MIT"}, wantErr: "variable 'module': value is required"},
+		{name: "empty required", values: map[string]any{"module": "", "license": "MIT"}, wantErr: "value is required"},
+		{name: "not a choice", values: map[string]any{"module": "shop", "license": "GPL"}, wantErr: "'GPL' is not one of [MIT Apache-2.0]"},
+		{name: "pattern mismatch", values: map[string]any{"module": "Shop"}, wantErr: "'Shop' does not match pattern"},
+		{name: "secret is redacted", values: map[string]any{"module": "shop", "token": "hunter2"}, wantErr: "'" + Redacted + "' does not match"},
+		{name: "inactive variable is not checked", values: map[string]any{"module": "shop", "use_db": false}},
+		{name: "active variable is checked", values: map[string]any{"module": "shop", "use_db": true}, wantErr: "variable 'db_driver': value is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tmpl.ValidateValues(tt.values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateValues() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateValues() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	parent := loadTemplateText(t, `
+strict = true
+[metadata]
+name = "base"
+author = "Naviary"
+[variables]
+name = {default = "app"}
+port = {default = 8080}
+[rules]
+ignores = ["*.tmp"]
+renames = {"a.txt" = "b.txt"}
+[hooks]
+post_apply = ["git init"]
+`)
+	child := loadTemplateText(t, `
+extends = "base"
+[metadata]
+name = "web"
+[variables]
+db = {default = "postgres"}
+port = {default = 3000}
+[rules]
+ignores = ["*.log"]
+renames = {"a.txt" = "c.txt"}
+[hooks]
+post_apply = ["npm install"]
+`)
+
+	merged := child.Merge(parent)
+	if got, want := variableNames(merged.OrderedVariables()), []string{"name", "port", "db"}; !slices.Equal(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+	if got := merged.Variables["port"].Default; got != int64(3000) {
+		t.Errorf("port default = %v, want 3000", got)
+	}
+	if got, want := merged.Rules.Ignores, []string{"*.tmp", "*.log"}; !slices.Equal(got, want) {
+		t.Errorf("ignores = %v, want %v", got, want)
+	}
+	if got := merged.Rules.Renames["a.txt"]; got != "c.txt" {
+		t.Errorf("rename of a.txt = %q, want c.txt", got)
+	}
+	if got, want := merged.Hooks.PostApply, []string{"git init", "npm install"}; !slices.Equal(got, want) {
+		t.Errorf("post_apply = %v, want %v", got, want)
+	}
+	if !merged.Strict || merged.Metadata.Name != "web" || merged.Metadata.Author != "Naviary" {
+		t.Errorf("merged = strict %v, name %q, author %q", merged.Strict, merged.Metadata.Name, merged.Metadata.Author)
+	}
+}
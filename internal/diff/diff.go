@@ -0,0 +1,205 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is how many unchanged lines surround each change.
+const contextLines = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	// a and b are the line indexes in the old and new text
+	a, b int
+}
+
+// Unified returns a unified diff from oldText to newText labelled with
+// oldName and newName, or "" when they are equal.
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	a, b := splitLines(oldText), splitLines(newText)
+	ops := lineOps(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of its hunk
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				last = i
+				continue
+			}
+			if i-last > 2*contextLines {
+				break
+			}
+		}
+
+		from := max(first-contextLines, start)
+		to := min(last+contextLines+1, len(ops))
+		writeHunk(&out, ops[from:to], a, b)
+		start = to
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []op, a, b []string) {
+	aStart, bStart := -1, -1
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			if aStart < 0 {
+				aStart = o.a
+			}
+			aCount++
+		}
+		if o.kind != opDelete {
+			if bStart < 0 {
+				bStart = o.b
+			}
+			bCount++
+		}
+	}
+	// An empty range is reported at the line before it
+	if aStart < 0 {
+		aStart = ops[0].a - 1
+	}
+	if bStart < 0 {
+		bStart = ops[0].b - 1
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			writeLine(out, " ", a[o.a])
+		case opDelete:
+			writeLine(out, "-", a[o.a])
+		case opInsert:
+			writeLine(out, "+", b[o.b])
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeLine(out *strings.Builder, prefix, line string) {
+	out.WriteString(prefix)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// splitLines splits text after each newline, keeping the newlines so a
+// missing one at the end of the file shows up in the diff.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps returns the shortest edit script turning a into b, using Myers'
+// O(ND) algorithm.
+func lineOps(a, b []string) []op {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds v for diagonals -d..d before step d
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, n, m int) []op {
+	var ops []op
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{kind: opInsert, a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, op{kind: opDelete, a: x, b: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{kind: opEqual, a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
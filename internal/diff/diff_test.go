@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines 1..n, one per line, with replacements applied.
+func numbered(n int, replace map[int]string) string {
+	var text strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := replace[i]; ok {
+			text.WriteString(line + "\n")
+			continue
+		}
+		fmt.Fprintf(&text, "%d\n", i)
+	}
+	return text.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "x\ny\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "emptied file",
+			old:  "a\nb\nc\n",
+			new:  "",
+			want: "--- old\n+++ new\n@@ -1,3 +0,0 @@\n-a\n-b\n-c\n",
+		},
+		{
+			name: "missing newline at end",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "distant changes in separate hunks",
+			old:  numbered(15, nil),
+			new:  numbered(15, map[int]string{2: "two", 14: "fourteen"}),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -11,5 +11,5 @@\n 11\n 12\n 13\n-14\n+fourteen\n 15\n",
+		},
+		{
+			name: "close changes in one hunk",
+			old:  numbered(15, nil),
+			new:  numbered(15, map[int]string{2: "two", 8: "eight"}),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,11 +1,11 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n",
+		},
+		{
+			name: "single line ranges",
+			old:  "a\n",
+			new:  "b\n",
+			want: "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.old, tt.new); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineOpsEditScript(t *testing.T) {
+	tests := []struct {
+		a, b string
+		// edits is the number of inserted and deleted lines in the shortest script
+		edits int
+	}{
+		{a: "", b: "", edits: 0},
+		{a: "abc", b: "abc", edits: 0},
+		{a: "abcabba", b: "cbabac", edits: 5},
+		{a: "abc", b: "", edits: 3},
+		{a: "", b: "xyz", edits: 3},
+		{a: "abcdef", b: "xbcdey", edits: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"->"+tt.b, func(t *testing.T) {
+			a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+			ops := lineOps(a, b)
+
+			var rebuilt []string
+			edits := 0
+			for _, o := range ops {
+				switch o.kind {
+				case opEqual:
+					if a[o.a] != b[o.b] {
+						t.Fatalf("equal op pairs %q with %q", a[o.a], b[o.b])
+					}
+					rebuilt = append(rebuilt, a[o.a])
+				case opInsert:
+					rebuilt = append(rebuilt, b[o.b])
+					edits++
+				case opDelete:
+					edits++
+				}
+			}
+			if got := strings.Join(rebuilt, ""); got != tt.b {
+				t.Errorf("edit script builds %q, want %q", got, tt.b)
+			}
+			if edits != tt.edits {
+				t.Errorf("edit script has %d edits, want %d", edits, tt.edits)
+			}
+		})
+	}
+}
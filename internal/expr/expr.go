@@ -0,0 +1,136 @@
+// Package expr holds what template files, paths, hooks and variable
+// conditions share: the functions available to them and helpers over
+// parsed templates.
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/casing"
+)
+
+// Funcs are available in every template:
+//
+//	{{upper .name}} {{lower .name}}
+//	{{camel .name}} {{pascal .name}} {{snake .name}} {{kebab .name}}
+var Funcs = template.FuncMap{
+	"upper":  stringFunc(strings.ToUpper),
+	"lower":  stringFunc(strings.ToLower),
+	"camel":  stringFunc(casing.Camel),
+	"pascal": stringFunc(casing.Pascal),
+	"snake":  stringFunc(casing.Snake),
+	"kebab":  stringFunc(casing.Kebab),
+}
+
+// stringFunc accepts any value, so numbers and booleans can be passed too.
+func stringFunc(fn func(string) string) func(any) string {
+	return func(value any) string {
+		return fn(fmt.Sprint(value))
+	}
+}
+
+// Parse parses text as a template named name, with Funcs available.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs).Parse(text)
+}
+
+// Truthy reports whether rendered text counts as true in a condition:
+// anything but "", "false", "0" and "<no value>", ignoring surrounding
+// space and case.
+func Truthy(text string) bool {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "", "false", "0", "<no value>":
+		return false
+	}
+	return true
+}
+
+// Reference is a use of a variable in a template: its path, such as
+// ["config", "port"] for .config.port, and its byte offset in the text.
+type Reference struct {
+	Path []string
+	Pos  parse.Pos
+}
+
+// Name is the top-level variable the reference starts with.
+func (r Reference) Name() string {
+	return r.Path[0]
+}
+
+// Line returns the 1-based line of the reference in text, the source the
+// template was parsed from.
+func (r Reference) Line(text string) int {
+	pos := min(int(r.Pos), len(text))
+	return strings.Count(text[:pos], "\n") + 1
+}
+
+// References returns every use of a template variable under node, in the
+// order they appear: .name where dot is the data the template was executed
+// with, and $.name anywhere. Fields inside range and with, where dot is
+// something else, are left out.
+func References(node parse.Node) []Reference {
+	var references []Reference
+	var walk func(node parse.Node, root bool)
+	walk = func(node parse.Node, root bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, root)
+			}
+		case *parse.FieldNode:
+			if root {
+				references = append(references, Reference{Path: n.Ident, Pos: n.Pos})
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				references = append(references, Reference{Path: n.Ident[1:], Pos: n.Pos})
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		}
+	}
+	walk(node, true)
+	return references
+}
+
+// Fields returns the top-level variables, such as name for .name,
+// referenced under node; see References.
+func Fields(node parse.Node) []string {
+	references := References(node)
+	names := make([]string, len(references))
+	for i, reference := range references {
+		names[i] = reference.Name()
+	}
+	return names
+}
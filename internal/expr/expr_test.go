@@ -0,0 +1,61 @@
+package expr
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"plain", "{{.name}}", []string{"name"}},
+		{"repeated", "{{.name}} and {{.name}}", []string{"name", "name"}},
+		{"nested", "{{.config.port}}", []string{"config.port"}},
+		{"function argument", "{{upper .name}}", []string{"name"}},
+		{"pipeline", "{{.name | lower}}", []string{"name"}},
+		{"if and else", "{{if .a}}{{.b}}{{else}}{{.c}}{{end}}", []string{"a", "b", "c"}},
+		{"range body is not the root", "{{range .items}}{{.field}}{{end}}", []string{"items"}},
+		{"range else is the root", "{{range .items}}x{{else}}{{.empty}}{{end}}", []string{"items", "empty"}},
+		{"with body is not the root", "{{with .user}}{{.name}}{{end}}", []string{"user"}},
+		{"dollar reaches the root", "{{range .items}}{{$.name}}{{end}}", []string{"items", "name"}},
+		{"local variables", "{{$x := .name}}{{$x}}", []string{"name"}},
+		{"no references", "plain text {{\"literal\"}}", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			var got []string
+			for _, reference := range References(tmpl.Tree.Root) {
+				got = append(got, strings.Join(reference.Path, "."))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("References() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceLine(t *testing.T) {
+	text := "first {{.a}}\nsecond\n\n{{if .b}}\n  {{.a}}\n{{end}}"
+	tmpl, err := Parse("lines", text)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var lines []int
+	for _, reference := range References(tmpl.Tree.Root) {
+		lines = append(lines, reference.Line(text))
+	}
+	if want := []int{1, 4, 5}; !slices.Equal(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
@@ -0,0 +1,134 @@
+// Package extract turns literal values in an existing project back into
+// template expressions, the reverse of rendering.
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/casing"
+)
+
+// Replacement is one spelling of a variable's value and the expression that
+// renders it.
+type Replacement struct {
+	Variable   string `json:"variable"`
+	Text       string `json:"text"`
+	Expression string `json:"expression"`
+}
+
+// escapedDelimiter keeps "{{" already in a project from being read as an
+// action when the template is parsed.
+const escapedDelimiter = `{{"{{"}}`
+
+// Variants returns value as written followed by its case variants, e.g.
+// for "payments-api": PaymentsApi, paymentsApi, payments_api, PAYMENTS-API
+// and PAYMENTS_API. Spellings that coincide are listed once.
+func Variants(variable, value string) []Replacement {
+	candidates := []Replacement{
+		{Text: value, Expression: fmt.Sprintf("{{.%s}}", variable)},
+		{Text: casing.Pascal(value), Expression: fmt.Sprintf("{{pascal .%s}}", variable)},
+		{Text: casing.Camel(value), Expression: fmt.Sprintf("{{camel .%s}}", variable)},
+		{Text: casing.Snake(value), Expression: fmt.Sprintf("{{snake .%s}}", variable)},
+		{Text: casing.Kebab(value), Expression: fmt.Sprintf("{{kebab .%s}}", variable)},
+		{Text: strings.ToUpper(value), Expression: fmt.Sprintf("{{upper .%s}}", variable)},
+		{Text: strings.ToUpper(casing.Snake(value)), Expression: fmt.Sprintf("{{upper (snake .%s)}}", variable)},
+		{Text: strings.ToLower(value), Expression: fmt.Sprintf("{{lower .%s}}", variable)},
+	}
+
+	seen := make(map[string]bool)
+	var variants []Replacement
+	for _, candidate := range candidates {
+		if candidate.Text == "" || seen[candidate.Text] {
+			continue
+		}
+		seen[candidate.Text] = true
+		candidate.Variable = variable
+		variants = append(variants, candidate)
+	}
+	return variants
+}
+
+// Replacer replaces every variant of a set of values in text.
+type Replacer struct {
+	replacements []Replacement
+}
+
+// NewReplacer builds a replacer for values, keyed by variable name. When
+// two variables share a spelling, the variable that sorts first keeps it.
+func NewReplacer(values map[string]string) (*Replacer, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	replacer := &Replacer{}
+	for _, name := range names {
+		if values[name] == "" {
+			return nil, fmt.Errorf("variable '%s' has an empty value", name)
+		}
+		for _, variant := range Variants(name, values[name]) {
+			if seen[variant.Text] {
+				continue
+			}
+			seen[variant.Text] = true
+			replacer.replacements = append(replacer.replacements, variant)
+		}
+	}
+
+	// The longest spelling wins where several match, so "PaymentsApi" is
+	// not split into "{{pascal .x}}Api"
+	sort.SliceStable(replacer.replacements, func(i, j int) bool {
+		return len(replacer.replacements[i].Text) > len(replacer.replacements[j].Text)
+	})
+	return replacer, nil
+}
+
+// Replacements lists every spelling the replacer looks for, longest first.
+func (replacer *Replacer) Replacements() []Replacement {
+	return replacer.replacements
+}
+
+// Replace returns text with each variant replaced by its expression and
+// existing "{{" escaped. counts is incremented per replaced spelling.
+func (replacer *Replacer) Replace(text string, counts map[string]int) string {
+	var out strings.Builder
+
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "{{") {
+			out.WriteString(escapedDelimiter)
+			i += 2
+			continue
+		}
+
+		matched := false
+		for _, replacement := range replacer.replacements {
+			if strings.HasPrefix(text[i:], replacement.Text) {
+				out.WriteString(replacement.Expression)
+				i += len(replacement.Text)
+				if counts != nil {
+					counts[replacement.Text]++
+				}
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out.WriteByte(text[i])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// ReplacePath replaces variants in each element of a slash-separated path.
+func (replacer *Replacer) ReplacePath(path string, counts map[string]int) string {
+	elements := strings.Split(path, "/")
+	for i, element := range elements {
+		elements[i] = replacer.Replace(element, counts)
+	}
+	return strings.Join(elements, "/")
+}
@@ -0,0 +1,111 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+)
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]string
+		text       string
+		want       string
+		wantCounts map[string]int
+	}{
+		{
+			name:       "as written",
+			values:     map[string]string{"service": "payments"},
+			text:       "package payments",
+			want:       "package {{.service}}",
+			wantCounts: map[string]int{"payments": 1},
+		},
+		{
+			name:       "case variants",
+			values:     map[string]string{"service": "payments"},
+			text:       "type Payments struct{} // PAYMENTS",
+			want:       "type {{pascal .service}} struct{} // {{upper .service}}",
+			wantCounts: map[string]int{"Payments": 1, "PAYMENTS": 1},
+		},
+		{
+			name:   "longest match wins",
+			values: map[string]string{"service": "payments_svc"},
+			text:   "PaymentsSvc paymentsSvc payments_svc payments-svc PAYMENTS_SVC",
+			want:   "{{pascal .service}} {{camel .service}} {{.service}} {{kebab .service}} {{upper .service}}",
+		},
+		{
+			name:   "longer value of another variable wins",
+			values: map[string]string{"a": "pay", "b": "payments"},
+			text:   "pay payments",
+			want:   "{{.a}} {{.b}}",
+		},
+		{
+			name:   "existing delimiters are escaped",
+			values: map[string]string{"service": "payments"},
+			text:   "{{ .Values.payments }} and }}",
+			want:   `{{"{{"}} .Values.{{.service}} }} and }}`,
+		},
+		{
+			name:   "no match",
+			values: map[string]string{"service": "payments"},
+			text:   "billing",
+			want:   "billing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replacer, err := NewReplacer(tt.values)
+			if err != nil {
+				t.Fatalf("NewReplacer() error = %v", err)
+			}
+
+			counts := make(map[string]int)
+			got := replacer.Replace(tt.text, counts)
+			if got != tt.want {
+				t.Errorf("Replace() = %q, want %q", got, tt.want)
+			}
+			for text, want := range tt.wantCounts {
+				if counts[text] != want {
+					t.Errorf("counts[%q] = %d, want %d", text, counts[text], want)
+				}
+			}
+
+			// Rendering the result with the same values gives the text back
+			tmpl, err := expr.Parse(tt.name, got)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var rendered strings.Builder
+			data := make(map[string]any)
+			for name, value := range tt.values {
+				data[name] = value
+			}
+			if err := tmpl.Execute(&rendered, data); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if rendered.String() != tt.text {
+				t.Errorf("rendered = %q, want the original %q", rendered.String(), tt.text)
+			}
+		})
+	}
+}
+
+func TestNewReplacerEmptyValue(t *testing.T) {
+	if _, err := NewReplacer(map[string]string{"service": ""}); err == nil {
+		t.Error("NewReplacer() with an empty value succeeded, want an error")
+	}
+}
+
+func TestReplacePath(t *testing.T) {
+	replacer, err := NewReplacer(map[string]string{"service": "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := replacer.ReplacePath("cmd/payments/Payments.go", nil)
+	if want := "cmd/{{.service}}/{{pascal .service}}.go"; got != want {
+		t.Errorf("ReplacePath() = %q, want %q", got, want)
+	}
+}
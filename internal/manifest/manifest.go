@@ -0,0 +1,169 @@
+// Package manifest records what tg generated into a project, so later runs
+// can tell which files were changed by hand.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// FileName is written to the root of every directory tg apply generates.
+const FileName = ".tg-manifest.json"
+
+type Manifest struct {
+	Templates []Entry `json:"templates"`
+}
+
+// Entry is one template applied to the project.
+type Entry struct {
+	Name        string         `json:"name"`
+	Version     string         `json:"version,omitempty"`
+	TGVersion   string         `json:"tg_version"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Variables   map[string]any `json:"variables"`
+	// Files maps each generated path, slash-separated, to its content hash
+	Files map[string]string `json:"files"`
+	// Patched lists the paths in Files that the template's actions only
+	// edited; they belong to the project, not the template
+	Patched []string `json:"patched,omitempty"`
+	// Generators records each tg gen run in the project, in order
+	Generators []GeneratorRun `json:"generators,omitempty"`
+	// Migration is set while an upgrade has run migration steps but not
+	// rendered the new version yet
+	Migration *MigrationState `json:"migration,omitempty"`
+}
+
+// GeneratorRun is one run of a template's generator, recorded so an upgrade
+// can run it again on the new version. Secret variables are left out.
+type GeneratorRun struct {
+	Name      string         `json:"name"`
+	Variables map[string]any `json:"variables"`
+	// Files lists the paths the generator created
+	Files []string `json:"files,omitempty"`
+}
+
+// MigrationState records how far an unfinished upgrade got: every migration
+// up to Version ran, and the first Steps steps of the one to To. The entry's
+// Version stays the version the files were last rendered with.
+type MigrationState struct {
+	Version string `json:"version"`
+	To      string `json:"to,omitempty"`
+	Steps   int    `json:"steps,omitempty"`
+}
+
+// Load reads the manifest in dir. A missing file is an empty manifest.
+func Load(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", filepath.Join(dir, FileName), err)
+	}
+	return &manifest, nil
+}
+
+func (manifest *Manifest) Save(dir string) error {
+	sort.Slice(manifest.Templates, func(i, j int) bool {
+		return manifest.Templates[i].Name < manifest.Templates[j].Name
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Get returns the entry for the template name.
+func (manifest *Manifest) Get(name string) (Entry, bool) {
+	for _, entry := range manifest.Templates {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Set adds or replaces the entry for entry.Name.
+func (manifest *Manifest) Set(entry Entry) {
+	for i := range manifest.Templates {
+		if manifest.Templates[i].Name == entry.Name {
+			manifest.Templates[i] = entry
+			return
+		}
+	}
+	manifest.Templates = append(manifest.Templates, entry)
+}
+
+// Generated returns the files the template's own render generated,
+// leaving out those it only patched and those its generators created.
+func (entry Entry) Generated() map[string]string {
+	generated := make(map[string]string, len(entry.Files))
+	for path, hash := range entry.Files {
+		if !slices.Contains(entry.Patched, path) && !entry.fromGenerator(path) {
+			generated[path] = hash
+		}
+	}
+	return generated
+}
+
+func (entry Entry) fromGenerator(path string) bool {
+	for _, run := range entry.Generators {
+		if slices.Contains(run.Files, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the content hash stored for a file, in the same form as
+// the hashes in apply reports.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// FileState is how a generated file compares with what is on disk now.
+type FileState string
+
+const (
+	FileUnchanged FileState = "unchanged"
+	FileModified  FileState = "modified"
+	FileDeleted   FileState = "deleted"
+)
+
+// Check compares every file of the entry with its copy under dir.
+func (entry Entry) Check(dir string) (map[string]FileState, error) {
+	states := make(map[string]FileState, len(entry.Files))
+	for path, hash := range entry.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		switch {
+		case os.IsNotExist(err):
+			states[path] = FileDeleted
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		case Hash(content) != hash:
+			states[path] = FileModified
+		default:
+			states[path] = FileUnchanged
+		}
+	}
+	return states, nil
+}
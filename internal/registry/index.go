@@ -0,0 +1,199 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/semver"
+)
+
+const (
+	fetchTimeout = 30 * time.Second
+	maxIndexSize = 16 << 20
+)
+
+// Index is a registry's list of templates and their released versions.
+type Index struct {
+	Templates []Entry `json:"templates"`
+
+	location string
+}
+
+type Entry struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Versions    []Release `json:"versions"`
+}
+
+// Release is one downloadable version of a template. URL may be relative
+// to the index location.
+type Release struct {
+	Version  string `json:"version"`
+	URL      string `json:"url"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// LoadIndex reads an index from an http(s) URL or a local path.
+func LoadIndex(location string) (*Index, error) {
+	data, err := fetch(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry index: %w", err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse registry index %s: %w", location, err)
+	}
+	index.location = location
+
+	return &index, nil
+}
+
+func fetch(location string) ([]byte, error) {
+	var reader io.Reader
+
+	if isURL(location) {
+		client := &http.Client{Timeout: fetchTimeout}
+		response, err := client.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned %s", location, response.Status)
+		}
+		reader = response.Body
+	} else {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxIndexSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIndexSize {
+		return nil, fmt.Errorf("index is larger than %d bytes", maxIndexSize)
+	}
+	return data, nil
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// Search returns the entries whose name, description or tags contain query,
+// case-insensitively, sorted by name. An empty query matches everything.
+func (index *Index) Search(query string) []Entry {
+	query = strings.ToLower(query)
+
+	var matches []Entry
+	for _, entry := range index.Templates {
+		if query == "" || entry.matches(query) {
+			matches = append(matches, entry)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+func (entry Entry) matches(query string) bool {
+	if strings.Contains(strings.ToLower(entry.Name), query) ||
+		strings.Contains(strings.ToLower(entry.Description), query) {
+		return true
+	}
+	for _, tag := range entry.Tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the highest released version, ignoring prereleases.
+func (entry Entry) Latest() (Release, bool) {
+	release, err := entry.Resolve("*")
+	return release, err == nil
+}
+
+// Resolve picks the highest release that satisfies constraint.
+func (entry Entry) Resolve(constraint string) (Release, error) {
+	parsed, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return Release{}, err
+	}
+
+	var versions []semver.Version
+	releases := make(map[string]Release)
+	for _, release := range entry.Versions {
+		version, err := semver.Parse(release.Version)
+		if err != nil {
+			// Releases with invalid versions can never be selected
+			continue
+		}
+		versions = append(versions, version)
+		releases[version.String()] = release
+	}
+
+	best, ok := parsed.Best(versions)
+	if !ok {
+		return Release{}, fmt.Errorf("no version of '%s' matches '%s'", entry.Name, parsed)
+	}
+	return releases[best.String()], nil
+}
+
+// Find returns the entry named name.
+func (index *Index) Find(name string) (Entry, bool) {
+	for _, entry := range index.Templates {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// DownloadURL resolves a release URL against the index location, so indexes
+// can list bundles next to themselves.
+func (index *Index) DownloadURL(release Release) (string, error) {
+	if isURL(release.URL) || filepath.IsAbs(release.URL) {
+		return release.URL, nil
+	}
+
+	if isURL(index.location) {
+		base, err := url.Parse(index.location)
+		if err != nil {
+			return "", fmt.Errorf("invalid registry location: %w", err)
+		}
+		ref, err := url.Parse(release.URL)
+		if err != nil {
+			return "", fmt.Errorf("invalid release url '%s': %w", release.URL, err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+
+	return filepath.Join(filepath.Dir(index.location), filepath.FromSlash(release.URL)), nil
+}
+
+// ParseReference splits "name@constraint". Without "@" the constraint is
+// empty and matches the latest release.
+func ParseReference(reference string) (name, constraint string) {
+	name, constraint, _ = strings.Cut(reference, "@")
+	return name, constraint
+}
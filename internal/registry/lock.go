@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pelletier/go-toml"
+)
+
+const LockFile = "tg.lock"
+
+// Lock records exactly which template revisions were installed, so everyone
+// working on a project generates from the same templates.
+type Lock struct {
+	Templates []Locked `toml:"templates"`
+}
+
+type Locked struct {
+	Name     string `toml:"name"`
+	Version  string `toml:"version"`
+	Source   string `toml:"source"`
+	Checksum string `toml:"checksum"`
+}
+
+// LoadLock reads a lockfile. A missing file is an empty lock.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Lock{}, nil
+		}
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	var lock Lock
+	if err := toml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+func (lock *Lock) Save(path string) error {
+	sort.Slice(lock.Templates, func(i, j int) bool {
+		return lock.Templates[i].Name < lock.Templates[j].Name
+	})
+
+	data, err := toml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	header := []byte("# This file is generated by tg install. Do not edit it by hand.\n")
+	if err := os.WriteFile(path, append(header, data...), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// Get returns the locked entry for name.
+func (lock *Lock) Get(name string) (Locked, bool) {
+	for _, locked := range lock.Templates {
+		if locked.Name == name {
+			return locked, true
+		}
+	}
+	return Locked{}, false
+}
+
+// Set adds or replaces the entry for locked.Name.
+func (lock *Lock) Set(locked Locked) {
+	for i := range lock.Templates {
+		if lock.Templates[i].Name == locked.Name {
+			lock.Templates[i] = locked
+			return
+		}
+	}
+	lock.Templates = append(lock.Templates, locked)
+}
@@ -0,0 +1,243 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a version range in the npm/cargo style, e.g. "^1.2",
+// "~1.2.3", "1.x", ">=1.0 <2.0" or "1.x || 2.x".
+type Constraint struct {
+	text         string
+	alternatives [][]comparator
+}
+
+type comparator struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint reads a constraint. An empty constraint or "*" matches
+// every release.
+func ParseConstraint(text string) (*Constraint, error) {
+	constraint := &Constraint{text: strings.TrimSpace(text)}
+
+	for _, alternative := range strings.Split(constraint.text, "||") {
+		fields := strings.FieldsFunc(alternative, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+
+		comparators := []comparator{}
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between an operator and its version: ">= 1.2"
+			if isOperator(field) && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+
+			expanded, err := expand(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint '%s': %w", text, err)
+			}
+			comparators = append(comparators, expanded...)
+		}
+		constraint.alternatives = append(constraint.alternatives, comparators)
+	}
+
+	return constraint, nil
+}
+
+func (constraint *Constraint) String() string {
+	if constraint.text == "" {
+		return "*"
+	}
+	return constraint.text
+}
+
+// Check reports whether version satisfies the constraint. Prereleases only
+// match when the constraint names a prerelease of the same version, so
+// "^1.2" never selects "1.3.0-beta".
+func (constraint *Constraint) Check(version Version) bool {
+	for _, comparators := range constraint.alternatives {
+		if matchAll(comparators, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the highest of versions that satisfies the constraint.
+func (constraint *Constraint) Best(versions []Version) (Version, bool) {
+	var best Version
+	found := false
+	for _, version := range versions {
+		if !constraint.Check(version) {
+			continue
+		}
+		if !found || best.LessThan(version) {
+			best = version
+			found = true
+		}
+	}
+	return best, found
+}
+
+func matchAll(comparators []comparator, version Version) bool {
+	for _, c := range comparators {
+		if !c.match(version) {
+			return false
+		}
+	}
+
+	if version.Prerelease == "" {
+		return true
+	}
+	for _, c := range comparators {
+		v := c.version
+		if v.Prerelease != "" && v.Major == version.Major && v.Minor == version.Minor && v.Patch == version.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparator) match(version Version) bool {
+	result := version.Compare(c.version)
+	switch c.op {
+	case "=":
+		return result == 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	}
+	return false
+}
+
+func isOperator(field string) bool {
+	switch field {
+	case "=", ">", ">=", "<", "<=", "^", "~":
+		return true
+	}
+	return false
+}
+
+// partial is a version where trailing parts may be missing or wildcards.
+type partial struct {
+	version Version
+	parts   int
+}
+
+func parsePartial(text string) (partial, error) {
+	text = strings.TrimPrefix(text, "v")
+	if text == "" || text == "*" || text == "x" || text == "X" {
+		return partial{}, nil
+	}
+
+	if strings.ContainsAny(text, "-+") {
+		version, err := Parse(text)
+		if err != nil {
+			return partial{}, err
+		}
+		return partial{version: version, parts: 3}, nil
+	}
+
+	fields := strings.Split(text, ".")
+	if len(fields) > 3 {
+		return partial{}, fmt.Errorf("'%s' has too many parts", text)
+	}
+
+	var p partial
+	numbers := []*int{&p.version.Major, &p.version.Minor, &p.version.Patch}
+	for i, field := range fields {
+		if field == "*" || field == "x" || field == "X" {
+			break
+		}
+		number, err := parseNumber(field)
+		if err != nil {
+			return partial{}, err
+		}
+		*numbers[i] = number
+		p.parts++
+	}
+	return p, nil
+}
+
+// next returns the first version above every version the partial covers.
+func (p partial) next() Version {
+	switch p.parts {
+	case 1:
+		return Version{Major: p.version.Major + 1}
+	case 2:
+		return Version{Major: p.version.Major, Minor: p.version.Minor + 1}
+	default:
+		return Version{Major: p.version.Major, Minor: p.version.Minor, Patch: p.version.Patch + 1}
+	}
+}
+
+func expand(field string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(field, candidate) {
+			op = candidate
+			field = field[len(candidate):]
+			break
+		}
+	}
+
+	p, err := parsePartial(field)
+	if err != nil {
+		return nil, err
+	}
+	lower := p.version
+
+	if p.parts == 0 {
+		if op == "<" || op == ">" {
+			// Nothing is below or above every version
+			return []comparator{{op: "<", version: Version{}}}, nil
+		}
+		return nil, nil
+	}
+
+	switch op {
+	case "^":
+		upper := Version{Major: lower.Major + 1}
+		switch {
+		case lower.Major == 0 && p.parts >= 2 && lower.Minor > 0:
+			upper = Version{Minor: lower.Minor + 1}
+		case lower.Major == 0 && p.parts == 3:
+			upper = Version{Patch: lower.Patch + 1}
+		case lower.Major == 0 && p.parts == 2:
+			upper = Version{Minor: lower.Minor + 1}
+		}
+		return []comparator{{">=", lower}, {"<", upper}}, nil
+	case "~":
+		upper := Version{Major: lower.Major, Minor: lower.Minor + 1}
+		if p.parts == 1 {
+			upper = Version{Major: lower.Major + 1}
+		}
+		return []comparator{{">=", lower}, {"<", upper}}, nil
+	case ">":
+		if p.parts == 3 {
+			return []comparator{{">", lower}}, nil
+		}
+		return []comparator{{">=", p.next()}}, nil
+	case "<=":
+		if p.parts == 3 {
+			return []comparator{{"<=", lower}}, nil
+		}
+		return []comparator{{"<", p.next()}}, nil
+	case ">=", "<":
+		return []comparator{{op, lower}}, nil
+	default:
+		if p.parts == 3 {
+			return []comparator{{"=", lower}}, nil
+		}
+		return []comparator{{">=", lower}, {"<", p.next()}}, nil
+	}
+}
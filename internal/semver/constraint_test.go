@@ -0,0 +1,100 @@
+package semver
+
+import "testing"
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "", match: []string{"0.0.1", "9.9.9"}, noMatch: []string{"1.0.0-beta"}},
+		{constraint: "*", match: []string{"0.0.1", "9.9.9"}},
+		{constraint: "1.2.3", match: []string{"1.2.3", "1.2.3+build"}, noMatch: []string{"1.2.4"}},
+		{constraint: "^1.2", match: []string{"1.2.0", "1.9.9"}, noMatch: []string{"1.1.9", "2.0.0", "1.3.0-beta"}},
+		{constraint: "^1.2.3", match: []string{"1.2.3", "1.99.0"}, noMatch: []string{"1.2.2", "2.0.0"}},
+		{constraint: "^0.2.3", match: []string{"0.2.3", "0.2.9"}, noMatch: []string{"0.2.2", "0.3.0"}},
+		{constraint: "^0.2", match: []string{"0.2.0", "0.2.9"}, noMatch: []string{"0.3.0"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, noMatch: []string{"0.0.4", "0.1.0"}},
+		{constraint: "^0.0", match: []string{"0.0.0", "0.0.9"}, noMatch: []string{"0.1.0"}},
+		{constraint: "^0", match: []string{"0.0.0", "0.9.9"}, noMatch: []string{"1.0.0"}},
+		{constraint: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, noMatch: []string{"1.2.2", "1.3.0"}},
+		{constraint: "~1.2", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: "~1", match: []string{"1.0.0", "1.9.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: "1.x", match: []string{"1.0.0", "1.9.9"}, noMatch: []string{"0.9.9", "2.0.0"}},
+		{constraint: "1.2.*", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: ">=1.0 <2.0", match: []string{"1.0.0", "1.9.9"}, noMatch: []string{"0.9.9", "2.0.0"}},
+		{constraint: ">= 1.0, < 2.0", match: []string{"1.5.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: "> 1.2", match: []string{"1.3.0"}, noMatch: []string{"1.2.9"}},
+		{constraint: ">1.2.3", match: []string{"1.2.4"}, noMatch: []string{"1.2.3"}},
+		{constraint: "<=1.2", match: []string{"1.2.9"}, noMatch: []string{"1.3.0"}},
+		{constraint: "<1.2", match: []string{"1.1.9"}, noMatch: []string{"1.2.0"}},
+		{constraint: "1.x || 3.x", match: []string{"1.5.0", "3.0.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: ">*", noMatch: []string{"0.0.0", "1.0.0"}},
+		{constraint: "^1.0.0-beta", match: []string{"1.0.0-beta", "1.0.0-beta.2", "1.0.0", "1.5.0"}, noMatch: []string{"1.0.0-alpha", "1.1.0-beta"}},
+		{constraint: "<2.0.0", match: []string{"1.9.9"}, noMatch: []string{"2.0.0-beta"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			for _, text := range tt.match {
+				if !constraint.Check(MustParse(text)) {
+					t.Errorf("Check(%s) = false, want true", text)
+				}
+			}
+			for _, text := range tt.noMatch {
+				if constraint.Check(MustParse(text)) {
+					t.Errorf("Check(%s) = true, want false", text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, text := range []string{"^1.2.3.4", "~a", ">=1.0.0-", "1.02"} {
+		if _, err := ParseConstraint(text); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestConstraintBest(t *testing.T) {
+	var versions []Version
+	for _, text := range []string{"1.0.0", "1.4.2", "1.5.0-rc.1", "2.0.0", "0.9.0"} {
+		versions = append(versions, MustParse(text))
+	}
+
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{constraint: "^1.0", want: "1.4.2"},
+		{constraint: "*", want: "2.0.0"},
+		{constraint: "<1.0", want: "0.9.0"},
+		{constraint: "^3", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			got, ok := constraint.Best(versions)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Best() = %s, want no match", got)
+				}
+				return
+			}
+			if !ok || got.String() != tt.want {
+				t.Errorf("Best() = %s, %v, want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
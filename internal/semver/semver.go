@@ -0,0 +1,178 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version as described at https://semver.org.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// Parse reads a strict semantic version. A leading "v" is accepted.
+func Parse(text string) (Version, error) {
+	var version Version
+	rest := strings.TrimPrefix(text, "v")
+
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		version.Build = rest[i+1:]
+		rest = rest[:i]
+		if err := validateIdentifiers(version.Build, false); err != nil {
+			return Version{}, fmt.Errorf("invalid version '%s': build %w", text, err)
+		}
+	}
+
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		version.Prerelease = rest[i+1:]
+		rest = rest[:i]
+		if err := validateIdentifiers(version.Prerelease, true); err != nil {
+			return Version{}, fmt.Errorf("invalid version '%s': prerelease %w", text, err)
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version '%s': expected MAJOR.MINOR.PATCH", text)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := parseNumber(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version '%s': %w", text, err)
+		}
+		numbers[i] = number
+	}
+
+	version.Major, version.Minor, version.Patch = numbers[0], numbers[1], numbers[2]
+	return version, nil
+}
+
+// MustParse is Parse for versions known to be valid, such as constants.
+func MustParse(text string) Version {
+	version, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return version
+}
+
+func parseNumber(part string) (int, error) {
+	if part == "" {
+		return 0, fmt.Errorf("empty version number")
+	}
+	if len(part) > 1 && part[0] == '0' {
+		return 0, fmt.Errorf("version number '%s' has a leading zero", part)
+	}
+	for _, r := range part {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("'%s' is not a number", part)
+		}
+	}
+	return strconv.Atoi(part)
+}
+
+func validateIdentifiers(text string, numericNoLeadingZero bool) error {
+	for _, identifier := range strings.Split(text, ".") {
+		if identifier == "" {
+			return fmt.Errorf("has an empty identifier")
+		}
+		numeric := true
+		for _, r := range identifier {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("has an invalid character %q", r)
+			}
+		}
+		if numeric && numericNoLeadingZero && len(identifier) > 1 && identifier[0] == '0' {
+			return fmt.Errorf("identifier '%s' has a leading zero", identifier)
+		}
+	}
+	return nil
+}
+
+func (version Version) String() string {
+	text := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+	if version.Prerelease != "" {
+		text += "-" + version.Prerelease
+	}
+	if version.Build != "" {
+		text += "+" + version.Build
+	}
+	return text
+}
+
+// Compare returns -1, 0 or 1 when version is lower than, equal to or higher
+// than other. Build metadata is ignored, as the spec requires.
+func (version Version) Compare(other Version) int {
+	if c := compareInt(version.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(version.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(version.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(version.Prerelease, other.Prerelease)
+}
+
+func (version Version) LessThan(other Version) bool {
+	return version.Compare(other) < 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease orders prerelease identifiers; a version without a
+// prerelease is higher than one with.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		leftNumber, leftErr := strconv.Atoi(left[i])
+		rightNumber, rightErr := strconv.Atoi(right[i])
+
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if c := compareInt(leftNumber, rightNumber); c != 0 {
+				return c
+			}
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(left), len(right))
+}
@@ -0,0 +1,78 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Version
+		wantErr bool
+	}{
+		{text: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{text: "v0.10.0", want: Version{Minor: 10}},
+		{text: "1.0.0-alpha.1", want: Version{Major: 1, Prerelease: "alpha.1"}},
+		{text: "1.0.0-rc-1+build.5", want: Version{Major: 1, Prerelease: "rc-1", Build: "build.5"}},
+		{text: "1.0.0+001", want: Version{Major: 1, Build: "001"}},
+		{text: "1.0", wantErr: true},
+		{text: "1.0.0.0", wantErr: true},
+		{text: "01.0.0", wantErr: true},
+		{text: "1.a.0", wantErr: true},
+		{text: "1.0.0-", wantErr: true},
+		{text: "1.0.0-alpha..1", wantErr: true},
+		{text: "1.0.0-01", wantErr: true},
+		{text: "1.0.0-beta_1", wantErr: true},
+		{text: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := Parse(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparePrecedence(t *testing.T) {
+	// In ascending order, as listed in the spec
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, b := MustParse(ordered[i]), MustParse(ordered[j])
+			want := compareInt(i, j)
+			if got := a.Compare(b); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestCompareIgnoresBuild(t *testing.T) {
+	if got := MustParse("1.0.0+a").Compare(MustParse("1.0.0+b")); got != 0 {
+		t.Errorf("Compare() = %d, want 0", got)
+	}
+}
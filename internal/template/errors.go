@@ -0,0 +1,160 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Location points at a place in a template file. Line and Column are
+// 1-based; zero means unknown.
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Excerpt is the source line the location points at
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+func (location Location) String() string {
+	switch {
+	case location.Line == 0:
+		return location.File
+	case location.Column == 0:
+		return fmt.Sprintf("%s:%d", location.File, location.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", location.File, location.Line, location.Column)
+	}
+}
+
+// Caret returns the excerpt prefixed with its line number and, when the
+// column is known, a caret under the offending character:
+//
+//	3 | by {{.author}}
+//	  |       ^
+func (location Location) Caret() string {
+	if location.Excerpt == "" {
+		return ""
+	}
+
+	gutter := strconv.Itoa(location.Line)
+	text := fmt.Sprintf("%s | %s\n", gutter, location.Excerpt)
+	if location.Column > 0 && location.Column <= len(location.Excerpt)+1 {
+		// Keep tabs so the caret lines up however they are displayed
+		indent := []byte(location.Excerpt[:location.Column-1])
+		for i, c := range indent {
+			if c != '\t' {
+				indent[i] = ' '
+			}
+		}
+		text += fmt.Sprintf("%s | %s^\n", strings.Repeat(" ", len(gutter)), indent)
+	}
+	return text
+}
+
+// ParseError is a syntax error in a template file.
+type ParseError struct {
+	Location
+	Message string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: syntax error: %s", e.Location, e.Message)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ExecError is a failure while rendering the content of a template file.
+type ExecError struct {
+	Location
+	Message string
+	Err     error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Location, e.Message)
+}
+
+func (e *ExecError) Unwrap() error { return e.Err }
+
+// PathError is a failure parsing or rendering the output path of a template
+// file. The excerpt is the path itself.
+type PathError struct {
+	Location
+	Message string
+	Err     error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: output path: %s", e.Location, e.Message)
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+
+// ErrorLocation returns the location carried by a ParseError, ExecError or
+// PathError anywhere in err's chain.
+func ErrorLocation(err error) (Location, bool) {
+	var parseErr *ParseError
+	var execErr *ExecError
+	var pathErr *PathError
+
+	switch {
+	case errors.As(err, &parseErr):
+		return parseErr.Location, true
+	case errors.As(err, &execErr):
+		return execErr.Location, true
+	case errors.As(err, &pathErr):
+		return pathErr.Location, true
+	}
+	return Location{}, false
+}
+
+var errorPosition = regexp.MustCompile(`(?s)^(\d+)(?::(\d+))?: (.*)$`)
+
+// locate splits the "template: NAME:LINE[:COLUMN]: message" form used by
+// text/template for a template named file into a location and a message.
+// text is the template source, used for the excerpt.
+func locate(file, text string, err error) (Location, string) {
+	location := Location{File: file}
+	message := err.Error()
+
+	if rest, ok := strings.CutPrefix(message, "template: "+file+":"); ok {
+		if match := errorPosition.FindStringSubmatch(rest); match != nil {
+			location.Line, _ = strconv.Atoi(match[1])
+			if match[2] != "" {
+				// text/template counts columns from zero
+				column, _ := strconv.Atoi(match[2])
+				location.Column = column + 1
+			}
+			message = match[3]
+		}
+	}
+	message = strings.TrimPrefix(message, fmt.Sprintf(`executing "%s" `, file))
+
+	lines := strings.Split(text, "\n")
+	if location.Line > 0 && location.Line <= len(lines) {
+		location.Excerpt = strings.TrimRight(lines[location.Line-1], "\r")
+	}
+	return location, message
+}
+
+func newParseError(file, text string, err error) error {
+	location, message := locate(file, text, err)
+	return &ParseError{Location: location, Message: message, Err: err}
+}
+
+func newExecError(file, text string, err error) error {
+	location, message := locate(file, text, err)
+	return &ExecError{Location: location, Message: message, Err: err}
+}
+
+func newPathError(file, path string, err error) error {
+	location, message := locate(file, path, err)
+	if location.Line == 0 {
+		location.Excerpt = path
+	}
+	return &PathError{Location: location, Message: message, Err: err}
+}
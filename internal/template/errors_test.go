@@ -0,0 +1,113 @@
+package template
+
+import (
+	"io"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+)
+
+func TestErrorLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       func(t *testing.T) error
+		wantError string
+		wantLoc   Location
+		wantCaret string
+	}{
+		{
+			name: "parse error without column",
+			err: func(t *testing.T) error {
+				text := "line one\n\tby {{.author}\r\n"
+				_, err := expr.Parse("a.txt", text)
+				return newParseError("a.txt", text, err)
+			},
+			wantError: "a.txt:2: syntax error: bad character U+007D '}'",
+			wantLoc:   Location{File: "a.txt", Line: 2, Excerpt: "\tby {{.author}"},
+			wantCaret: "2 | \tby {{.author}\n",
+		},
+		{
+			name: "exec error with column after a tab",
+			err: func(t *testing.T) error {
+				text := "x\n\t{{.a.b}}\n"
+				tmpl, err := expr.Parse("b.txt", text)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = tmpl.Execute(io.Discard, map[string]any{"a": "s"})
+				return newExecError("b.txt", text, err)
+			},
+			wantError: "b.txt:2:6: at <.a.b>: can't evaluate field b in type interface {}",
+			wantLoc:   Location{File: "b.txt", Line: 2, Column: 6, Excerpt: "\t{{.a.b}}"},
+			wantCaret: "2 | \t{{.a.b}}\n  | \t    ^\n",
+		},
+		{
+			name: "path error",
+			err: func(t *testing.T) error {
+				path := "src/{{.name"
+				_, err := expr.Parse("src/name", path)
+				return newPathError("src/name", path, err)
+			},
+			wantError: "src/name:1: output path: unclosed action",
+			wantLoc:   Location{File: "src/name", Line: 1, Excerpt: "src/{{.name"},
+			wantCaret: "1 | src/{{.name\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err(t)
+			if got := err.Error(); got != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got, tt.wantError)
+			}
+
+			location, ok := ErrorLocation(err)
+			if !ok {
+				t.Fatal("ErrorLocation() found no location")
+			}
+			if location != tt.wantLoc {
+				t.Errorf("ErrorLocation() = %+v, want %+v", location, tt.wantLoc)
+			}
+			if got := location.Caret(); got != tt.wantCaret {
+				t.Errorf("Caret() = %q, want %q", got, tt.wantCaret)
+			}
+		})
+	}
+}
+
+func TestCaret(t *testing.T) {
+	tests := []struct {
+		name     string
+		location Location
+		want     string
+	}{
+		{
+			name:     "no excerpt",
+			location: Location{File: "a.txt", Line: 3, Column: 2},
+			want:     "",
+		},
+		{
+			name:     "first column",
+			location: Location{File: "a.txt", Line: 12, Column: 1, Excerpt: "{{.x}}"},
+			want:     "12 | {{.x}}\n   | ^\n",
+		},
+		{
+			name:     "column just past the end",
+			location: Location{File: "a.txt", Line: 1, Column: 4, Excerpt: "abc"},
+			want:     "1 | abc\n  |    ^\n",
+		},
+		{
+			name:     "column out of range",
+			location: Location{File: "a.txt", Line: 1, Column: 9, Excerpt: "abc"},
+			want:     "1 | abc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.location.Caret(); got != tt.want {
+				t.Errorf("Caret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
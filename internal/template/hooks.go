@@ -0,0 +1,42 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+// RunHooks renders each command with the processor's variables and runs it
+// through the system shell in dir, stopping at the first failure.
+func (processor *Processor) RunHooks(commands []string, dir string, stdout, stderr io.Writer) error {
+	for _, command := range commands {
+		tmpl, err := parseString("hook", command)
+		if err != nil {
+			return fmt.Errorf("failed to parse hook '%s': %w", command, err)
+		}
+
+		rendered, err := processor.execute(tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render hook '%s': %w", command, err)
+		}
+
+		cmd := shellCommand(rendered)
+		cmd.Dir = dir
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook '%s' failed: %w", rendered, err)
+		}
+	}
+
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,165 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+// MigrationResult records one migration step run in a project.
+type MigrationResult struct {
+	// Version is the version the step's migration upgrades to
+	Version string `json:"version"`
+	Op      string `json:"op"`
+	Target  string `json:"target"`
+	To      string `json:"to,omitempty"`
+	// Skipped is set when there was nothing to rename or delete
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// MigrationProgress is called after each migration step with the migration,
+// how many of its steps are done and the variables as they are now, so the
+// caller can record how far an upgrade got. An error stops Migrate.
+type MigrationProgress func(migration config.Migration, done int, variables map[string]any) error
+
+// Migrate runs the steps of migrations in the project directory dir,
+// leaving out the first skip steps of the first migration, which ran in an
+// earlier upgrade that did not finish. Variable renames apply to the
+// processor's variables, so later steps and Variables see the new names. It
+// stops at the first failing step; progress, if not nil, is called after
+// every step that succeeded.
+func (processor *Processor) Migrate(dir string, migrations []config.Migration, skip int, progress MigrationProgress, stdout, stderr io.Writer) ([]MigrationResult, error) {
+	processor.variables = maps.Clone(processor.variables)
+	if processor.variables == nil {
+		processor.variables = make(map[string]any)
+	}
+
+	var results []MigrationResult
+	for m, migration := range migrations {
+		for i, step := range migration.Steps {
+			if m == 0 && i < skip {
+				continue
+			}
+
+			result, err := processor.migrateStep(dir, step, stdout, stderr)
+			if err != nil {
+				return results, fmt.Errorf("migration to %s, step #%d: %w", migration.To, i+1, err)
+			}
+			result.Version = migration.To
+			results = append(results, result)
+
+			if progress != nil {
+				if err := progress(migration, i+1, processor.variables); err != nil {
+					return results, err
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// Variables returns the values the processor renders with.
+func (processor *Processor) Variables() map[string]any {
+	return processor.variables
+}
+
+func (processor *Processor) migrateStep(dir string, step config.MigrationStep, stdout, stderr io.Writer) (MigrationResult, error) {
+	op, argument := step.Op()
+	result := MigrationResult{Op: op, Target: argument, To: step.To}
+
+	if op == config.OpRenameVariable {
+		value, ok := processor.variables[argument]
+		if !ok {
+			result.Skipped = true
+			return result, nil
+		}
+		delete(processor.variables, argument)
+		processor.variables[step.To] = value
+		return result, nil
+	}
+
+	target, err := processor.renderString(argument)
+	if err != nil {
+		return result, fmt.Errorf("failed to render %s '%s': %w", op, argument, err)
+	}
+	result.Target = target
+
+	if op == config.OpRun {
+		cmd := shellCommand(target)
+		cmd.Dir = dir
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return result, fmt.Errorf("command '%s' failed: %w", target, err)
+		}
+		return result, nil
+	}
+
+	if err := checkProjectPath(dir, target); err != nil {
+		return result, err
+	}
+	source := filepath.Join(dir, filepath.FromSlash(target))
+	if _, err := os.Lstat(source); os.IsNotExist(err) {
+		result.Skipped = true
+		return result, nil
+	}
+
+	if op == config.OpDelete {
+		if err := os.RemoveAll(source); err != nil {
+			return result, fmt.Errorf("failed to delete %s: %w", target, err)
+		}
+		return result, nil
+	}
+
+	to, err := processor.renderString(step.To)
+	if err != nil {
+		return result, fmt.Errorf("failed to render to '%s': %w", step.To, err)
+	}
+	if err := checkProjectPath(dir, to); err != nil {
+		return result, err
+	}
+	result.To = to
+
+	destination := filepath.Join(dir, filepath.FromSlash(to))
+	if _, err := os.Lstat(destination); err == nil {
+		return result, fmt.Errorf("cannot rename %s to %s: %s already exists", target, to, to)
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return result, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(source, destination); err != nil {
+		return result, fmt.Errorf("failed to rename %s: %w", target, err)
+	}
+	return result, nil
+}
+
+// checkProjectPath is checkOutputPath for a path that a migration renames or
+// deletes in the project dir, also refusing one whose parent directories
+// lead through a symlink out of dir. The path itself may be a symlink:
+// renaming or deleting it leaves what it points to alone.
+func checkProjectPath(dir, path string) error {
+	if err := checkOutputPath(path); err != nil {
+		return err
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	err = checkInside(root, realRoot, filepath.Dir(filepath.FromSlash(path)))
+	var unsafe *UnsafePathError
+	if errors.As(err, &unsafe) {
+		unsafe.Path = path
+	}
+	return err
+}
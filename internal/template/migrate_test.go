@@ -0,0 +1,135 @@
+package template
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestMigrateResume(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrations := []config.Migration{
+		{From: "1.*", To: "2.0.0", Steps: []config.MigrationStep{
+			{Delete: "a.txt"},
+			{RenameVariable: "name", To: "project"},
+			{RenamePath: "b.txt", To: "{{.project}}.txt"},
+		}},
+		{From: "2.*", To: "3.0.0", Steps: []config.MigrationStep{
+			{Delete: "{{.project}}.txt"},
+		}},
+	}
+
+	type call struct {
+		to   string
+		done int
+	}
+	var calls []call
+	progress := func(migration config.Migration, done int, variables map[string]any) error {
+		calls = append(calls, call{migration.To, done})
+		return nil
+	}
+
+	// The first step ran in an earlier upgrade; a.txt must be left alone
+	processor := NewProcessor(&config.Template{}, map[string]any{"name": "shop"})
+	results, err := processor.Migrate(dir, migrations, 1, progress, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Errorf("Migrate() ran %d steps, want 3", len(results))
+	}
+	want := []call{{"2.0.0", 2}, {"2.0.0", 3}, {"3.0.0", 1}}
+	if !slices.Equal(calls, want) {
+		t.Errorf("progress calls = %v, want %v", calls, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Errorf("a.txt was touched by a skipped step: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shop.txt")); !os.IsNotExist(err) {
+		t.Errorf("shop.txt should have been renamed and then deleted")
+	}
+	if got := processor.Variables()["project"]; got != "shop" {
+		t.Errorf("project = %v, want shop", got)
+	}
+}
+
+func TestMigrateProgressError(t *testing.T) {
+	dir := t.TempDir()
+	migrations := []config.Migration{
+		{From: "1.*", To: "2.0.0", Steps: []config.MigrationStep{
+			{Run: "echo one"},
+			{Run: "echo two"},
+		}},
+	}
+
+	stop := errors.New("manifest not writable")
+	processor := NewProcessor(&config.Template{}, nil)
+	results, err := processor.Migrate(dir, migrations, 0, func(config.Migration, int, map[string]any) error {
+		return stop
+	}, io.Discard, io.Discard)
+
+	if !errors.Is(err, stop) {
+		t.Fatalf("Migrate() error = %v, want %v", err, stop)
+	}
+	if len(results) != 1 {
+		t.Errorf("Migrate() ran %d steps after progress failed, want 1", len(results))
+	}
+}
+
+func TestMigrateSymlinkedParent(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim.txt")
+	if err := os.WriteFile(victim, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		step config.MigrationStep
+	}{
+		{"delete", config.MigrationStep{Delete: "link/victim.txt"}},
+		{"rename from", config.MigrationStep{RenamePath: "link/victim.txt", To: "moved.txt"}},
+		{"rename to", config.MigrationStep{RenamePath: "link", To: "link/inside"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations := []config.Migration{{From: "1.*", To: "2.0.0", Steps: []config.MigrationStep{tt.step}}}
+			processor := NewProcessor(&config.Template{}, nil)
+			_, err := processor.Migrate(dir, migrations, 0, nil, io.Discard, io.Discard)
+
+			var unsafe *UnsafePathError
+			if !errors.As(err, &unsafe) {
+				t.Fatalf("Migrate() error = %v, want an UnsafePathError", err)
+			}
+			if _, err := os.Stat(victim); err != nil {
+				t.Errorf("file outside the project was touched: %v", err)
+			}
+		})
+	}
+
+	// The link itself is inside the project and may go
+	migrations := []config.Migration{{From: "1.*", To: "2.0.0", Steps: []config.MigrationStep{{Delete: "link"}}}}
+	if _, err := NewProcessor(&config.Template{}, nil).Migrate(dir, migrations, 0, nil, io.Discard, io.Discard); err != nil {
+		t.Fatalf("deleting the link: %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("deleting the link removed its target: %v", err)
+	}
+}
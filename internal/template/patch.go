@@ -0,0 +1,239 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+// patch applies each action to files already in the sink, in order, so later
+// actions see earlier ones.
+func (processor *Processor) patch(sink Sink, actions []config.Action) ([]FileResult, error) {
+	var results []FileResult
+
+	for _, action := range actions {
+		start := time.Now()
+		op, argument := action.Op()
+
+		fileTmpl, err := parseString(action.File, action.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s file %s: %w", op, action.File, err)
+		}
+		file, err := processor.execute(fileTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s file %s: %w", op, action.File, err)
+		}
+		if err := checkOutputPath(file); err != nil {
+			return nil, processor.describeUnsafePath(err, action.File, fileTmpl)
+		}
+		file = filepath.Clean(filepath.FromSlash(file))
+
+		// Markers and replace patterns are matched as written; only the text
+		// that ends up in the file is rendered
+		text := action.Content
+		if op == config.OpAppend || op == config.OpPrepend {
+			text = argument
+		}
+		snippet, err := processor.renderString(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s content for %s: %w", op, file, err)
+		}
+
+		existing, err := sink.ReadFile(file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("cannot %s %s: file does not exist", op, file)
+			}
+			return nil, processor.describeUnsafePath(fmt.Errorf("failed to read %s: %w", file, err), action.File, fileTmpl)
+		}
+
+		content, changed, err := applyAction(string(existing), snippet, action)
+		if err != nil {
+			return nil, fmt.Errorf("cannot %s %s: %w", op, file, err)
+		}
+
+		result := FileResult{Source: action.File, Output: file, Action: ActionSkipped, Patch: true}
+		if changed {
+			if err := sink.WriteFile(file, []byte(content)); err != nil {
+				return nil, processor.describeUnsafePath(err, action.File, fileTmpl)
+			}
+			result.Action = patchResults[op]
+			result.Bytes = len(content)
+		}
+		result.Hash = hashContent([]byte(content))
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+var patchResults = map[string]Action{
+	config.OpAppend:       ActionAppended,
+	config.OpPrepend:      ActionPrepended,
+	config.OpInsertAfter:  ActionInjected,
+	config.OpInsertBefore: ActionInjected,
+	config.OpReplace:      ActionReplaced,
+}
+
+// renderString parses and executes a template string with the processor's
+// variables.
+func (processor *Processor) renderString(text string) (string, error) {
+	tmpl, err := parseString("action", text)
+	if err != nil {
+		return "", err
+	}
+	return processor.execute(tmpl)
+}
+
+// applyAction returns content with the rendered snippet applied and whether
+// anything changed.
+func applyAction(content, snippet string, action config.Action) (string, bool, error) {
+	op, argument := action.Op()
+	block := strings.TrimRight(snippet, "\n")
+
+	switch op {
+	case config.OpAppend:
+		if block == "" || strings.Contains(content, block) {
+			return content, false, nil
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + block + "\n", true, nil
+
+	case config.OpPrepend:
+		if block == "" || strings.Contains(content, block) {
+			return content, false, nil
+		}
+		return block + "\n" + content, true, nil
+
+	case config.OpReplace:
+		pattern, err := regexp.Compile(argument)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid pattern '%s': %w", argument, err)
+		}
+		return replaceMatches(content, snippet, argument, pattern)
+
+	default:
+		return insertSnippet(content, snippet, argument, action.Regex, op == config.OpInsertBefore)
+	}
+}
+
+// replaceMatches replaces each match of pattern with its expansion of
+// snippet. A match that already sits inside its replacement, as "foo" does
+// in "foobar" after replacing foo with foobar, is left alone so a second run
+// changes nothing.
+func replaceMatches(content, snippet, argument string, pattern *regexp.Regexp) (string, bool, error) {
+	matches := pattern.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		// Already replaced by an earlier run
+		if snippet != "" && strings.Contains(content, snippet) {
+			return content, false, nil
+		}
+		return "", false, fmt.Errorf("no match for pattern '%s'", argument)
+	}
+
+	var updated strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		replacement := string(pattern.ExpandString(nil, snippet, content, match))
+
+		updated.WriteString(content[last:start])
+		if replaced(content, start, end, replacement) {
+			updated.WriteString(content[start:end])
+		} else {
+			updated.WriteString(replacement)
+		}
+		last = end
+	}
+	updated.WriteString(content[last:])
+
+	return updated.String(), updated.String() != content, nil
+}
+
+// replaced reports whether the match content[start:end] is part of an
+// occurrence of replacement in content.
+func replaced(content string, start, end int, replacement string) bool {
+	match := content[start:end]
+	for offset := 0; offset+len(match) <= len(replacement); offset++ {
+		if replacement[offset:offset+len(match)] != match {
+			continue
+		}
+		from := start - offset
+		if from >= 0 && from+len(replacement) <= len(content) && content[from:from+len(replacement)] == replacement {
+			return true
+		}
+	}
+	return false
+}
+
+// insertSnippet places snippet on the lines after (or before) the first line
+// containing marker, indented like that line. It reports whether content
+// changed; a snippet that is already present is left alone.
+func insertSnippet(content, snippet, marker string, regex, before bool) (string, bool, error) {
+	lines := strings.SplitAfter(content, "\n")
+
+	anchor := -1
+	var pattern *regexp.Regexp
+	if regex {
+		var err error
+		if pattern, err = regexp.Compile(marker); err != nil {
+			return "", false, fmt.Errorf("invalid pattern '%s': %w", marker, err)
+		}
+	}
+	for i, line := range lines {
+		if (pattern != nil && pattern.MatchString(line)) || (pattern == nil && strings.Contains(line, marker)) {
+			anchor = i
+			break
+		}
+	}
+	if anchor < 0 {
+		if pattern != nil {
+			return "", false, fmt.Errorf("no line matches pattern '%s'", marker)
+		}
+		return "", false, fmt.Errorf("marker '%s' not found", marker)
+	}
+
+	anchorLine := lines[anchor]
+	indent := anchorLine[:len(anchorLine)-len(strings.TrimLeft(anchorLine, " \t"))]
+	block := indentLines(strings.TrimRight(snippet, "\n"), indent)
+
+	if strings.Contains(content, block) {
+		return content, false, nil
+	}
+
+	position := anchor + 1
+	if before {
+		position = anchor
+	} else if !strings.HasSuffix(anchorLine, "\n") {
+		// The anchor is the last line and has no newline to insert after
+		lines[anchor] += "\n"
+	}
+
+	updated := make([]string, 0, len(lines)+1)
+	updated = append(updated, lines[:position]...)
+	updated = append(updated, block+"\n")
+	updated = append(updated, lines[position:]...)
+	return strings.Join(updated, ""), true, nil
+}
+
+func indentLines(text, indent string) string {
+	if indent == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
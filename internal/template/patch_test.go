@@ -0,0 +1,125 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestApplyAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		snippet string
+		action  config.Action
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "append",
+			content: "a\nb",
+			snippet: "c",
+			action:  config.Action{Append: "c"},
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:    "prepend",
+			content: "b\n",
+			snippet: "a\n",
+			action:  config.Action{Prepend: "a"},
+			want:    "a\nb\n",
+		},
+		{
+			name:    "insert after marker, indented",
+			content: "func routes() {\n\t// tg:routes\n}\n",
+			snippet: "r.Handle(\"/user\")",
+			action:  config.Action{InsertAfter: "// tg:routes"},
+			want:    "func routes() {\n\t// tg:routes\n\tr.Handle(\"/user\")\n}\n",
+		},
+		{
+			name:    "insert before regex",
+			content: "# Handlers\n## End\n",
+			snippet: "- User",
+			action:  config.Action{InsertBefore: "^## End", Regex: true},
+			want:    "# Handlers\n- User\n## End\n",
+		},
+		{
+			name:    "insert after last line without newline",
+			content: "// tg:routes",
+			snippet: "x",
+			action:  config.Action{InsertAfter: "// tg:routes"},
+			want:    "// tg:routes\nx\n",
+		},
+		{
+			name:    "missing marker",
+			content: "nothing here\n",
+			snippet: "x",
+			action:  config.Action{InsertAfter: "// tg:routes"},
+			wantErr: true,
+		},
+		{
+			name:    "replace",
+			content: "port: 80\nhost: a\n",
+			snippet: "port: 8080",
+			action:  config.Action{Replace: `port: \d+`},
+			want:    "port: 8080\nhost: a\n",
+		},
+		{
+			name:    "replace with text containing the match",
+			content: "use foo here, and foo there\n",
+			snippet: "foobar",
+			action:  config.Action{Replace: "foo"},
+			want:    "use foobar here, and foobar there\n",
+		},
+		{
+			name:    "replace with text ending in the match",
+			content: "bar\n",
+			snippet: "foobar",
+			action:  config.Action{Replace: "bar"},
+			want:    "foobar\n",
+		},
+		{
+			name:    "replace with groups",
+			content: "version = 1\n",
+			snippet: "version = ${1}.0",
+			action:  config.Action{Replace: `version = (\d+)(\.0)?`},
+			want:    "version = 1.0\n",
+		},
+		{
+			name:    "replace without a match",
+			content: "nothing here\n",
+			snippet: "x",
+			action:  config.Action{Replace: "foo"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := applyAction(tt.content, tt.snippet, tt.action)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applyAction() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyAction() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("applyAction() = %q, want %q", got, tt.want)
+			}
+			if changed != (got != tt.content) {
+				t.Errorf("applyAction() changed = %v for %q -> %q", changed, tt.content, got)
+			}
+
+			again, changed, err := applyAction(got, tt.snippet, tt.action)
+			if err != nil {
+				t.Fatalf("second applyAction() error = %v", err)
+			}
+			if changed || again != got {
+				t.Errorf("second applyAction() = %q, changed = %v, want %q unchanged", again, changed, got)
+			}
+		})
+	}
+}
@@ -3,10 +3,10 @@ package template
 import (
 	"bytes"
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
+	"runtime"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/Naviary-Sanctuary/template_generator/internal/config"
 )
@@ -14,99 +14,283 @@ import (
 type Processor struct {
 	template  *config.Template
 	variables map[string]any
-}
-
-type ProcessResult struct {
-	FilesCreated int
-	DirsCreated  int
-	CreatedFiles []string
+	workers   int
+	strict    bool
 }
 
 func NewProcessor(template *config.Template, variables map[string]any) *Processor {
 	return &Processor{
 		template:  template,
 		variables: variables,
+		workers:   runtime.GOMAXPROCS(0),
+		strict:    template.Strict,
+	}
+}
+
+// SetWorkers limits how many files are rendered concurrently.
+func (processor *Processor) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
 	}
+	processor.workers = workers
 }
+
+// SetStrict makes references to undefined variables an error instead of
+// rendering "<no value>".
+func (processor *Processor) SetStrict(strict bool) {
+	processor.strict = strict
+}
+
+// Process renders the template into a staging area and moves the results into
+// outputDir only once every file has been rendered. On failure nothing is left
+// behind in outputDir.
 func (processor *Processor) Process(templateDir, outputDir string) (*ProcessResult, error) {
+	set, err := ParseDir(templateDir, processor.template.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return processor.ProcessSet(set, outputDir)
+}
+
+// ProcessSet renders an already parsed template set into outputDir.
+func (processor *Processor) ProcessSet(set *Set, outputDir string) (*ProcessResult, error) {
+	sink, err := NewDiskSink(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return processor.ProcessTo(set, sink)
+}
+
+// ProcessTo renders set into sink and commits it once every file has been
+// rendered. The sink is closed before returning.
+func (processor *Processor) ProcessTo(set *Set, sink Sink) (*ProcessResult, error) {
+	defer sink.Close()
+
+	if processor.strict {
+		if err := processor.CheckMissing(set); err != nil {
+			return nil, err
+		}
+	}
+
 	result := &ProcessResult{
 		CreatedFiles: make([]string, 0),
 	}
 
-	err := filepath.WalkDir(templateDir, func(path string, d fs.DirEntry, err error) error {
+	fileResults := make([]FileResult, len(set.entries))
+	sources := make(map[string]string)
+	var files []int
+
+	for i, e := range set.entries {
+		if e.ignored {
+			fileResults[i] = FileResult{Source: e.relativePath, Action: ActionIgnored}
+			continue
+		}
+
+		outputPath, err := processor.execute(e.path)
 		if err != nil {
-			return err
+			return nil, newPathError(e.relativePath, e.pathText, err)
+		}
+		if err := checkOutputPath(outputPath); err != nil {
+			return nil, processor.describeUnsafePath(err, e.relativePath, e.path)
 		}
+		fileResults[i] = FileResult{Source: e.relativePath, Output: outputPath}
 
-		if d.Name() == config.TemplateConfigFile {
-			return nil
+		if e.isDir {
+			if err := sink.Mkdir(outputPath); err != nil {
+				return nil, processor.describeUnsafePath(err, e.relativePath, e.path)
+			}
+			result.DirsCreated++
+			continue
 		}
 
-		relativePath, err := filepath.Rel(templateDir, path)
-		if err != nil {
-			return err
+		if source, ok := sources[outputPath]; ok {
+			return nil, fmt.Errorf("files %s and %s both generate %s", source, e.relativePath, outputPath)
 		}
+		sources[outputPath] = e.relativePath
+		files = append(files, i)
+	}
 
-		outputPath, err := processor.processString(filepath.Join(outputDir, relativePath))
+	errs := processor.render(len(files), func(job int) error {
+		i := files[job]
+		return processor.renderFile(sink, set.entries[i], &fileResults[i])
+	})
+
+	// Report the first failure in walk order so errors are deterministic
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("failed to process output path %s: %w", d.Name(), err)
+			return nil, err
 		}
+	}
 
-		if d.IsDir() {
-			if err := os.MkdirAll(outputPath, 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", outputPath, err)
-			}
-			result.DirsCreated++
-			return nil
+	patched, err := processor.patch(sink, processor.template.Actions)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range set.entries {
+		if e.isDir && !e.ignored {
+			continue
 		}
 
-		if err := processor.processFile(path, outputPath); err != nil {
-			return fmt.Errorf("failed to process file %s: %w", relativePath, err)
+		file := fileResults[i]
+		result.Files = append(result.Files, file)
+
+		if file.Action == ActionIgnored || file.Action == ActionSkipped {
+			continue
 		}
 
 		result.FilesCreated++
-		result.CreatedFiles = append(result.CreatedFiles, relativePath)
+		result.CreatedFiles = append(result.CreatedFiles, file.Source)
+	}
 
-		return nil
-	})
+	for _, file := range patched {
+		if file.Action != ActionSkipped {
+			result.FilesPatched++
+		}
+	}
+	result.Files = append(result.Files, patched...)
 
-	if err != nil {
+	if err := sink.Commit(); err != nil {
 		return nil, err
 	}
+
 	return result, nil
 }
 
-func (processor *Processor) processFile(path, outputPath string) error {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", path, err)
+// Plan resolves the output path of every entry in set without rendering any
+// content. Directories are left out; ignored files are reported as such.
+func (processor *Processor) Plan(set *Set) ([]FileResult, error) {
+	var files []FileResult
+
+	for _, e := range set.entries {
+		if e.ignored {
+			files = append(files, FileResult{Source: e.relativePath, Action: ActionIgnored})
+			continue
+		}
+		if e.isDir {
+			continue
+		}
+
+		outputPath, err := processor.execute(e.path)
+		if err != nil {
+			return nil, newPathError(e.relativePath, e.pathText, err)
+		}
+		if err := checkOutputPath(outputPath); err != nil {
+			return nil, processor.describeUnsafePath(err, e.relativePath, e.path)
+		}
+
+		action := ActionCreated
+		if e.content == nil {
+			action = ActionCopied
+		}
+		files = append(files, FileResult{Source: e.relativePath, Output: outputPath, Action: action})
 	}
 
-	processed, err := processor.processString(string(content))
-	if err != nil {
-		return fmt.Errorf("failed to process template %w", err)
+	return files, nil
+}
+
+// renderFile renders one file into the sink and fills in its result. Files
+// whose rendered content already matches the output are skipped.
+func (processor *Processor) renderFile(sink Sink, e *entry, file *FileResult) error {
+	start := time.Now()
+
+	content := e.raw
+	action := ActionCopied
+	if e.content != nil {
+		rendered, err := processor.execute(e.content)
+		if err != nil {
+			return newExecError(e.relativePath, e.text, err)
+		}
+		content = []byte(rendered)
+		action = ActionCreated
 	}
 
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create parent directory: %w", err)
+	file.Hash = hashContent(content)
+
+	if existing, err := sink.ReadFile(file.Output); err == nil {
+		if bytes.Equal(existing, content) {
+			file.Action = ActionSkipped
+			file.Duration = time.Since(start)
+			return nil
+		}
+		if action == ActionCreated {
+			action = ActionOverwritten
+		}
 	}
 
-	if err := os.WriteFile(outputPath, []byte(processed), 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", outputPath, err)
+	if err := sink.WriteFile(file.Output, content); err != nil {
+		return processor.describeUnsafePath(err, e.relativePath, e.path)
 	}
 
+	file.Action = action
+	file.Bytes = len(content)
+	file.Duration = time.Since(start)
 	return nil
 }
 
-func (processor *Processor) processString(content string) (string, error) {
-	tmpl, err := template.New("template").Parse(content)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+// render runs fn for every job on a bounded pool of workers. Once a job
+// fails, jobs that have not started yet are skipped.
+func (processor *Processor) render(jobs int, fn func(job int) error) []error {
+	errs := make([]error, jobs)
+	queue := make(chan int)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+
+	workers := min(processor.workers, jobs)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				mu.Lock()
+				skip := failed
+				mu.Unlock()
+				if skip {
+					continue
+				}
+
+				if err := fn(job); err != nil {
+					errs[job] = err
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return errs
+}
+
+func (processor *Processor) execute(tmpl *template.Template) (string, error) {
+	return processor.executeWith(tmpl, processor.variables)
+}
+
+func (processor *Processor) executeWith(tmpl *template.Template, variables map[string]any) (string, error) {
+	if processor.strict {
+		// Sets are shared, so the option goes on a copy
+		strict, err := tmpl.Clone()
+		if err != nil {
+			return "", err
+		}
+		tmpl = strict.Option("missingkey=error")
 	}
 
 	var buffer bytes.Buffer
-	if err := tmpl.Execute(&buffer, processor.variables); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	if err := tmpl.Execute(&buffer, variables); err != nil {
+		return "", err
 	}
 
 	return buffer.String(), nil
@@ -0,0 +1,181 @@
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+const benchFiles = 1000
+
+func newBenchTemplate(b *testing.B) (string, *config.Template, map[string]any) {
+	b.Helper()
+
+	templateDir := b.TempDir()
+	content := strings.Repeat("package {{.package}}\n\n// {{.project_name}} by {{.author}}\nfunc F() {}\n", 20)
+
+	for i := range benchFiles {
+		dir := filepath.Join(templateDir, fmt.Sprintf("pkg%02d", i%50))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("file_%d_{{.package}}.go", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	tmpl := &config.Template{Metadata: config.Metadata{Name: "bench"}}
+	variables := map[string]any{
+		"package":      "bench",
+		"project_name": "benchmark",
+		"author":       "tg",
+	}
+
+	return templateDir, tmpl, variables
+}
+
+// benchWorkers returns the worker counts to compare. They do not depend on
+// GOMAXPROCS, since writing files overlaps even on a single CPU.
+func benchWorkers() []int {
+	workers := []int{1, 2, 4, 8}
+	if n := runtime.GOMAXPROCS(0); !slices.Contains(workers, n) {
+		workers = append(workers, n)
+	}
+	return workers
+}
+
+// renderPerFile is the baseline the template set replaced: every file's path
+// and content is parsed again for each render, one file at a time while
+// walking the template. Files are kept in memory, so comparing it with
+// BenchmarkProcessTo measures parsing and rendering, not the disk.
+func renderPerFile(templateDir string, variables map[string]any) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(templateDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		output, err := renderText(relative, relative, variables)
+		if err != nil {
+			return err
+		}
+		rendered, err := renderText(relative, string(content), variables)
+		if err != nil {
+			return err
+		}
+		files[output] = []byte(rendered)
+		return nil
+	})
+	return files, err
+}
+
+func renderText(name, text string, variables map[string]any) (string, error) {
+	tmpl, err := parseString(name, text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, variables); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func BenchmarkRenderPerFile(b *testing.B) {
+	templateDir, _, variables := newBenchTemplate(b)
+
+	for b.Loop() {
+		if _, err := renderPerFile(templateDir, variables); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProcessTo renders a parsed set into memory, the counterpart of
+// BenchmarkRenderPerFile.
+func BenchmarkProcessTo(b *testing.B) {
+	templateDir, tmpl, variables := newBenchTemplate(b)
+
+	set, err := ParseDir(templateDir, tmpl.Rules)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range benchWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			processor := NewProcessor(tmpl, variables)
+			processor.SetWorkers(workers)
+
+			for b.Loop() {
+				if _, err := processor.ProcessTo(set, NewMemorySink(nil)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseDir(b *testing.B) {
+	templateDir, tmpl, _ := newBenchTemplate(b)
+
+	for b.Loop() {
+		if _, err := ParseDir(templateDir, tmpl.Rules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProcessSet(b *testing.B) {
+	templateDir, tmpl, variables := newBenchTemplate(b)
+
+	set, err := ParseDir(templateDir, tmpl.Rules)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range benchWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			processor := NewProcessor(tmpl, variables)
+			processor.SetWorkers(workers)
+
+			for b.Loop() {
+				if _, err := processor.ProcessSet(set, b.TempDir()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkProcess(b *testing.B) {
+	templateDir, tmpl, variables := newBenchTemplate(b)
+
+	for _, workers := range benchWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			processor := NewProcessor(tmpl, variables)
+			processor.SetWorkers(workers)
+
+			for b.Loop() {
+				if _, err := processor.Process(templateDir, b.TempDir()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
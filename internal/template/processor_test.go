@@ -0,0 +1,66 @@
+package template
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestProcessResultOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"skip.tmp": {Data: []byte("ignored\n")},
+	}
+	for i := range 200 {
+		// Uneven sizes make the workers finish out of order
+		content := strings.Repeat("{{.name}} ", 1+(i*7919)%500)
+		fsys[fmt.Sprintf("dir%d/file%03d_{{.name}}.txt", i%7, i)] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tmpl := &config.Template{
+		Metadata: config.Metadata{Name: "order"},
+		Rules:    config.Rules{Ignores: []string{"*.tmp"}},
+		Actions: []config.Action{
+			{File: "dir0/file000_shop.txt", Append: "appended"},
+		},
+	}
+	set, err := ParseFS(fsys, tmpl.Rules)
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+
+	var want []string
+	for _, e := range set.entries {
+		if !e.isDir {
+			want = append(want, e.relativePath)
+		}
+	}
+	want = append(want, "dir0/file000_shop.txt")
+
+	for _, workers := range []int{1, 4, 32} {
+		for run := range 5 {
+			processor := NewProcessor(tmpl, map[string]any{"name": "shop"})
+			processor.SetWorkers(workers)
+
+			result, err := processor.ProcessTo(set, NewMemorySink(nil))
+			if err != nil {
+				t.Fatalf("ProcessTo() error = %v", err)
+			}
+
+			var got []string
+			for _, file := range result.Files {
+				if file.Patch {
+					got = append(got, file.Output)
+				} else {
+					got = append(got, file.Source)
+				}
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("workers=%d run %d: Files order = %v, want walk order %v", workers, run, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Action describes what happened to a single template file.
+type Action string
+
+const (
+	ActionCreated     Action = "created"
+	ActionOverwritten Action = "overwritten"
+	ActionSkipped     Action = "skipped"
+	ActionCopied      Action = "copied"
+	ActionIgnored     Action = "ignored"
+	// Actions that patch a file already in the output directory
+	ActionInjected  Action = "injected"
+	ActionAppended  Action = "appended"
+	ActionPrepended Action = "prepended"
+	ActionReplaced  Action = "replaced"
+)
+
+// FileResult records the outcome for one file of the template.
+type FileResult struct {
+	Source   string        `json:"source"`
+	Output   string        `json:"output,omitempty"`
+	Action   Action        `json:"action"`
+	Bytes    int           `json:"bytes"`
+	Hash     string        `json:"hash,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	// Patch marks the result of an action on an existing file
+	Patch bool `json:"patch,omitempty"`
+}
+
+type ProcessResult struct {
+	FilesCreated int
+	DirsCreated  int
+	CreatedFiles []string
+	FilesPatched int
+	Files        []FileResult
+}
+
+// Count returns how many files ended with the given action.
+func (result *ProcessResult) Count(action Action) int {
+	count := 0
+	for _, file := range result.Files {
+		if file.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package template
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+// isIgnored reports whether a file under the template root should be left out
+// of the output. Includes take precedence over ignores: once any include
+// pattern is set, only matching files are generated.
+func isIgnored(rules config.Rules, relativePath string, isDir bool) bool {
+	slashPath := filepath.ToSlash(relativePath)
+	ignored := matchesAny(rules.Ignores, slashPath)
+
+	if len(rules.Includes) == 0 || isDir {
+		return ignored
+	}
+
+	return !matchesAny(rules.Includes, slashPath)
+}
+
+// renamePath applies rules.renames to a relative path. A key matches either
+// a leading part of the path (renaming a directory renames its contents) or
+// the file's base name.
+func renamePath(renames map[string]string, relativePath string) string {
+	if len(renames) == 0 {
+		return relativePath
+	}
+
+	segments := strings.Split(filepath.ToSlash(relativePath), "/")
+	for i := len(segments); i > 0; i-- {
+		prefix := strings.Join(segments[:i], "/")
+		if renamed, ok := renames[prefix]; ok {
+			rest := segments[i:]
+			return filepath.FromSlash(path.Join(append([]string{renamed}, rest...)...))
+		}
+	}
+
+	base := segments[len(segments)-1]
+	if renamed, ok := renames[base]; ok {
+		segments[len(segments)-1] = renamed
+		return filepath.FromSlash(strings.Join(segments, "/"))
+	}
+
+	return relativePath
+}
+
+func matchesAny(patterns []string, slashPath string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, slashPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a gitignore-like glob. Patterns without a slash match
+// any single path segment, so ".git" or "*.tmp" apply at every depth; other
+// patterns match the whole path and may use "**" for any number of segments.
+func matchPattern(pattern, slashPath string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	segments := strings.Split(slashPath, "/")
+
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for i := len(segments); i > 0; i-- {
+		if matchSegments(patternSegments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
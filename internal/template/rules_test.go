@@ -0,0 +1,176 @@
+package template
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Without a slash: any single segment, at any depth
+		{pattern: "*.tmp", path: "a.tmp", want: true},
+		{pattern: "*.tmp", path: "src/deep/a.tmp", want: true},
+		{pattern: "*.tmp", path: "a.tmp.go", want: false},
+		{pattern: "node_modules", path: "web/node_modules/x/index.js", want: true},
+		{pattern: "node_modules", path: "web/node_modules_old/index.js", want: false},
+		{pattern: "build/", path: "build/out.bin", want: true},
+		{pattern: "?.md", path: "docs/a.md", want: true},
+		{pattern: "[ab].md", path: "c.md", want: false},
+		// With a slash: from the template root, matching the path or a parent
+		{pattern: "docs/*.md", path: "docs/a.md", want: true},
+		{pattern: "docs/*.md", path: "web/docs/a.md", want: false},
+		{pattern: "/docs", path: "docs/a.md", want: true},
+		{pattern: "docs/internal", path: "docs/internal/a.md", want: true},
+		{pattern: "src/*", path: "src/pkg/a.go", want: true},
+		// "**" matches any number of segments, including none
+		{pattern: "**/*.go", path: "main.go", want: true},
+		{pattern: "**/*.go", path: "a/b/c.go", want: true},
+		{pattern: "**/*.go", path: "a/b/c.txt", want: false},
+		{pattern: "src/**/test", path: "src/test/a.go", want: true},
+		{pattern: "src/**/test", path: "src/a/b/test/c.go", want: true},
+		{pattern: "src/**/test", path: "lib/test/c.go", want: false},
+		{pattern: "src/**", path: "src/a/b.go", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchPattern(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules config.Rules
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{name: "no rules", path: "main.go", want: false},
+		{name: "ignored file", rules: config.Rules{Ignores: []string{"*.tmp"}}, path: "a.tmp", want: true},
+		{name: "ignored directory", rules: config.Rules{Ignores: []string{"node_modules"}}, path: "node_modules", isDir: true, want: true},
+		{name: "file not included", rules: config.Rules{Includes: []string{"**/*.go"}}, path: "README.md", want: true},
+		{name: "file included", rules: config.Rules{Includes: []string{"**/*.go"}}, path: "cmd/main.go", want: false},
+		{name: "includes never exclude directories", rules: config.Rules{Includes: []string{"**/*.go"}}, path: "cmd", isDir: true, want: false},
+		{name: "included directory includes its files", rules: config.Rules{Includes: []string{"docs"}}, path: "docs/guide/a.md", want: false},
+		{
+			name:  "include wins over ignore",
+			rules: config.Rules{Ignores: []string{"*.go"}, Includes: []string{"**/*.go"}},
+			path:  "main.go",
+			want:  false,
+		},
+		{
+			name:  "ignores have no effect on files once includes are set",
+			rules: config.Rules{Ignores: []string{"vendor"}, Includes: []string{"**/*.go"}},
+			path:  "vendor/lib/lib.go",
+			want:  false,
+		},
+		{
+			name:  "ignored directory with includes set",
+			rules: config.Rules{Ignores: []string{"vendor"}, Includes: []string{"**/*.go"}},
+			path:  "vendor",
+			isDir: true,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isIgnored(tt.rules, filepath.FromSlash(tt.path), tt.isDir); got != tt.want {
+				t.Errorf("isIgnored(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenamePath(t *testing.T) {
+	renames := map[string]string{
+		"README.template.md": "README.md",
+		"gitignore":          ".gitignore",
+		"app":                "{{.name}}",
+		"app/config":         "settings",
+		"docs/old.md":        "docs/new.md",
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "main.go", want: "main.go"},
+		{path: "README.template.md", want: "README.md"},
+		{path: "docs/README.template.md", want: "docs/README.md"},
+		{path: "web/gitignore", want: "web/.gitignore"},
+		{path: "app", want: "{{.name}}"},
+		{path: "app/main.go", want: "{{.name}}/main.go"},
+		{path: "app/config/dev.toml", want: "settings/dev.toml"},
+		{path: "docs/old.md", want: "docs/new.md"},
+		{path: "other/app/main.go", want: "other/app/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := filepath.ToSlash(renamePath(renames, filepath.FromSlash(tt.path)))
+			if got != tt.want {
+				t.Errorf("renamePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.template.md":   {Data: []byte("# {{.name}}\n")},
+		"app/main.go":          {Data: []byte("package main\n")},
+		"app/notes.tmp":        {Data: []byte("scratch\n")},
+		"vendor/lib/lib.go":    {Data: []byte("package lib\n")},
+		"vendor/lib/README.md": {Data: []byte("lib\n")},
+	}
+	tmpl := &config.Template{
+		Rules: config.Rules{
+			Ignores: []string{"vendor", "*.tmp"},
+			Renames: map[string]string{"README.template.md": "README.md", "app": "{{.name}}"},
+		},
+	}
+
+	set, err := ParseFS(fsys, tmpl.Rules)
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	sink := NewMemorySink(nil)
+	result, err := NewProcessor(tmpl, map[string]any{"name": "shop"}).ProcessTo(set, sink)
+	if err != nil {
+		t.Fatalf("ProcessTo() error = %v", err)
+	}
+
+	want := []string{"README.md", "shop/main.go"}
+	var got []string
+	for _, name := range slices.Sorted(maps.Keys(sink.Files())) {
+		got = append(got, filepath.ToSlash(name))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	var ignored []string
+	for _, file := range result.Files {
+		if file.Action == ActionIgnored {
+			ignored = append(ignored, filepath.ToSlash(file.Source))
+		}
+	}
+	slices.Sort(ignored)
+	if want := []string{"app/notes.tmp", "vendor"}; !slices.Equal(ignored, want) {
+		t.Errorf("ignored = %v, want %v", ignored, want)
+	}
+}
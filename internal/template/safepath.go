@@ -0,0 +1,142 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+)
+
+// protectedDir is never written to, whatever the template renders.
+const protectedDir = ".git"
+
+// UnsafePathError reports a rendered output path that would land outside
+// the output directory or inside a protected directory.
+type UnsafePathError struct {
+	// Source is the template file or action the path was rendered from
+	Source string
+	Path   string
+	Reason string
+	// Variables holds the values of the variables used in the path, with
+	// secret ones redacted
+	Variables map[string]any
+}
+
+func (e *UnsafePathError) Error() string {
+	message := fmt.Sprintf("unsafe output path '%s'", e.Path)
+	if e.Source != "" {
+		message += fmt.Sprintf(" from %s", e.Source)
+	}
+	message += ": " + e.Reason
+
+	if len(e.Variables) > 0 {
+		names := make([]string, 0, len(e.Variables))
+		for name := range e.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = fmt.Sprintf("%s=%q", name, fmt.Sprint(e.Variables[name]))
+		}
+		message += fmt.Sprintf(" (%s)", strings.Join(values, ", "))
+	}
+	return message
+}
+
+// checkOutputPath rejects absolute paths, paths that climb out of the output
+// root and paths into .git. It does not look at the filesystem; sinks that
+// write to disk also refuse symlinks leading outside the root.
+func checkOutputPath(path string) error {
+	reason := ""
+	switch {
+	case path == "":
+		reason = "path is empty"
+	case filepath.IsAbs(path) || filepath.VolumeName(path) != "" || strings.HasPrefix(filepath.ToSlash(path), "/"):
+		reason = "absolute paths are not allowed"
+	case !filepath.IsLocal(path) && filepath.Clean(path) != ".":
+		reason = "path escapes the output directory"
+	}
+	if reason == "" {
+		for _, segment := range strings.Split(filepath.ToSlash(filepath.Clean(path)), "/") {
+			if strings.EqualFold(segment, protectedDir) {
+				reason = fmt.Sprintf("writing into %s is not allowed", protectedDir)
+				break
+			}
+		}
+	}
+
+	if reason != "" {
+		return &UnsafePathError{Path: path, Reason: reason}
+	}
+	return nil
+}
+
+// checkInside refuses a path below root whose existing part leads through a
+// symlink to somewhere outside realRoot, which is root with its symlinks
+// resolved. root must be absolute.
+func checkInside(root, realRoot, relativePath string) error {
+	existing := filepath.Join(root, relativePath)
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		if existing == root {
+			return nil
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", existing, err)
+	}
+
+	inside, err := filepath.Rel(realRoot, resolved)
+	if err != nil || !filepath.IsLocal(inside) && inside != "." {
+		link, _ := filepath.Rel(root, existing)
+		return &UnsafePathError{
+			Path:   relativePath,
+			Reason: fmt.Sprintf("%s is a symlink to %s, outside the output directory", link, resolved),
+		}
+	}
+	return nil
+}
+
+// describeUnsafePath fills in the source and variable values, secrets
+// redacted, of an UnsafePathError raised for a path rendered from tmpl and
+// returns it. Other errors are returned unchanged.
+func (processor *Processor) describeUnsafePath(err error, source string, tmpl *template.Template) error {
+	var unsafe *UnsafePathError
+	if !errors.As(err, &unsafe) {
+		return err
+	}
+
+	unsafe.Source = source
+	unsafe.Variables = make(map[string]any)
+	if tmpl != nil && tmpl.Tree != nil {
+		for _, name := range expr.Fields(tmpl.Tree.Root) {
+			value, ok := processor.variables[name]
+			if !ok {
+				continue
+			}
+			if processor.template.Variables[name].Secret {
+				// The rendered path holds the value too
+				if text := fmt.Sprint(value); text != "" {
+					unsafe.Path = strings.ReplaceAll(unsafe.Path, text, config.Redacted)
+					unsafe.Reason = strings.ReplaceAll(unsafe.Reason, text, config.Redacted)
+				}
+				value = config.Redacted
+			}
+			unsafe.Variables[name] = value
+		}
+	}
+	return unsafe
+}
@@ -0,0 +1,198 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestUnsafePathErrorRedactsSecrets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{{.token}}/{{.dir}}.txt": {Data: []byte("x\n")},
+	}
+	tmpl := &config.Template{
+		Variables: map[string]config.Variable{
+			"dir":   {},
+			"token": {Secret: true},
+		},
+	}
+	values := map[string]any{"dir": "../../outside", "token": "s3cr3t-value"}
+
+	set, err := ParseFS(fsys, tmpl.Rules)
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	_, err = NewProcessor(tmpl, values).ProcessTo(set, NewMemorySink(nil))
+
+	var unsafe *UnsafePathError
+	if !errors.As(err, &unsafe) {
+		t.Fatalf("ProcessTo() error = %v, want an UnsafePathError", err)
+	}
+	message := unsafe.Error()
+	if strings.Contains(message, "s3cr3t-value") {
+		t.Errorf("error shows the secret: %s", message)
+	}
+	for _, want := range []string{`token="` + config.Redacted + `"`, `dir="../../outside"`, config.Redacted + "/../../outside.txt"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("error %q does not contain %q", message, want)
+		}
+	}
+}
+
+func TestCheckOutputPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "main.go"},
+		{path: "src/app/main.go"},
+		{path: "."},
+		{path: "a/../b.txt"},
+		{path: "..file"},
+		{path: ".github/workflows/ci.yml"},
+		{path: ".gitignore"},
+		{path: "git/config"},
+		{path: "", wantErr: true},
+		{path: "..", wantErr: true},
+		{path: "../outside.txt", wantErr: true},
+		{path: "a/../../outside.txt", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+		{path: "//server/share", wantErr: true},
+		{path: ".git", wantErr: true},
+		{path: ".git/config", wantErr: true},
+		{path: ".GIT/hooks/pre-commit", wantErr: true},
+		{path: "sub/.Git/config", wantErr: true},
+		{path: "a/../.git/config", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := checkOutputPath(filepath.FromSlash(tt.path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			var unsafe *UnsafePathError
+			if err != nil && !errors.As(err, &unsafe) {
+				t.Errorf("checkOutputPath(%q) error = %v, want an UnsafePathError", tt.path, err)
+			}
+		})
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+}
+
+func TestTransactionCheckTarget(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "out")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{filepath.Join(out, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, outside, filepath.Join(out, "escape"))
+	symlink(t, filepath.Join(outside, "secret.txt"), filepath.Join(out, "secret.txt"))
+	symlink(t, filepath.Join(outside, "missing.txt"), filepath.Join(out, "dangling.txt"))
+	symlink(t, "sub", filepath.Join(out, "inner"))
+	symlink(t, "..", filepath.Join(out, "sub", "up"))
+
+	tests := []struct {
+		path       string
+		wantErr    bool
+		wantUnsafe bool
+	}{
+		{path: "new.txt"},
+		{path: "sub/new.txt"},
+		{path: "not/yet/created.txt"},
+		{path: "inner/new.txt"},
+		{path: "sub/up/sub/new.txt"},
+		{path: "escape", wantErr: true, wantUnsafe: true},
+		{path: "escape/new.txt", wantErr: true, wantUnsafe: true},
+		{path: "escape/deep/new.txt", wantErr: true, wantUnsafe: true},
+		{path: "secret.txt", wantErr: true, wantUnsafe: true},
+		{path: "dangling.txt", wantErr: true},
+	}
+
+	tx, err := newTransaction(out)
+	if err != nil {
+		t.Fatalf("newTransaction() error = %v", err)
+	}
+	defer tx.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := tx.checkTarget(filepath.FromSlash(tt.path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkTarget(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			var unsafe *UnsafePathError
+			if tt.wantUnsafe && !errors.As(err, &unsafe) {
+				t.Errorf("checkTarget(%q) error = %v, want an UnsafePathError", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestTransactionSymlinkedOutputDir(t *testing.T) {
+	root := t.TempDir()
+	realDir := filepath.Join(root, "real")
+	if err := os.Mkdir(realDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	symlink(t, realDir, link)
+
+	tx, err := newTransaction(link)
+	if err != nil {
+		t.Fatalf("newTransaction() error = %v", err)
+	}
+	defer tx.Close()
+	if err := tx.checkTarget("main.go"); err != nil {
+		t.Errorf("checkTarget() error = %v", err)
+	}
+}
+
+func TestProcessRefusesSymlinkedParent(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "out")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{out, outside} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink(t, outside, filepath.Join(out, "config"))
+
+	fsys := fstest.MapFS{
+		"main.go":         {Data: []byte("package main\n")},
+		"config/app.toml": {Data: []byte("name = \"{{.name}}\"\n")},
+	}
+	set, err := ParseFS(fsys, config.Rules{})
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	_, err = NewProcessor(&config.Template{}, map[string]any{"name": "shop"}).ProcessSet(set, out)
+
+	var unsafe *UnsafePathError
+	if !errors.As(err, &unsafe) {
+		t.Fatalf("ProcessSet() error = %v, want an UnsafePathError", err)
+	}
+	for _, path := range []string{filepath.Join(outside, "app.toml"), filepath.Join(out, "main.go")} {
+		if _, err := os.Lstat(path); err == nil {
+			t.Errorf("%s was written", path)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+)
+
+// Set is a template directory parsed once, ready to be rendered any number
+// of times with different variables.
+type Set struct {
+	entries []*entry
+}
+
+type entry struct {
+	relativePath string
+	isDir        bool
+	ignored      bool
+	path         *template.Template
+	content      *template.Template
+
+	// pathText and text are the sources of path and content, kept for
+	// error excerpts
+	pathText string
+	text     string
+
+	// raw holds binary files, which are copied without rendering
+	raw []byte
+}
+
+// ParseDir walks templateDir and parses every file and path that survives
+// rules. Ignored files are recorded but never read.
+func ParseDir(templateDir string, rules config.Rules) (*Set, error) {
+	return ParseFS(os.DirFS(templateDir), rules)
+}
+
+// ParseFS is ParseDir for a template at the root of fsys, such as an
+// embed.FS narrowed with fs.Sub.
+func ParseFS(fsys fs.FS, rules config.Rules) (*Set, error) {
+	set := &Set{}
+
+	// skipped records the directories that lost an entry to rules.Skip
+	skipped := make(map[string]bool)
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Name() == config.TemplateConfigFile {
+			return nil
+		}
+
+		// A template checked out with git carries its own repository
+		if d.IsDir() && d.Name() == protectedDir {
+			return fs.SkipDir
+		}
+
+		if isSkipped(rules.Skip, path) {
+			skipped[filepath.Dir(filepath.FromSlash(path))] = true
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		relativePath := filepath.FromSlash(path)
+
+		e := &entry{
+			relativePath: relativePath,
+			isDir:        d.IsDir(),
+		}
+
+		if relativePath != "." && isIgnored(rules, relativePath, e.isDir) {
+			e.ignored = true
+			set.entries = append(set.entries, e)
+			if e.isDir && len(rules.Includes) == 0 {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		e.pathText = renamePath(rules.Renames, relativePath)
+		e.path, err = parseString(relativePath, e.pathText)
+		if err != nil {
+			return newPathError(relativePath, e.pathText, err)
+		}
+
+		if !e.isDir {
+			content, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+
+			if isBinary(content) {
+				e.raw = content
+			} else {
+				e.text = string(content)
+				e.content, err = parseString(relativePath, e.text)
+				if err != nil {
+					return newParseError(relativePath, e.text, err)
+				}
+			}
+		}
+
+		set.entries = append(set.entries, e)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	set.pruneSkipped(skipped)
+	return set, nil
+}
+
+// isSkipped reports whether path is one of skip or inside one of them.
+func isSkipped(skip []string, path string) bool {
+	for _, prefix := range skip {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneSkipped drops directories that are only empty because everything in
+// them was skipped, such as the generators directory holding every
+// generator. Directories that are empty in the template are kept.
+func (s *Set) pruneSkipped(skipped map[string]bool) {
+	kept := make(map[string]int)
+	drop := make(map[*entry]bool)
+
+	// Entries are in walk order, so children come after their directory
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if e.relativePath == "." {
+			continue
+		}
+		parent := filepath.Dir(e.relativePath)
+		if e.isDir && skipped[e.relativePath] && kept[e.relativePath] == 0 {
+			drop[e] = true
+			skipped[parent] = true
+			continue
+		}
+		kept[parent]++
+	}
+
+	s.entries = slices.DeleteFunc(s.entries, func(e *entry) bool { return drop[e] })
+}
+
+// Overlay returns a set with child's files layered over set's, as used when a
+// template extends another. Files at the same relative path are replaced.
+func (set *Set) Overlay(child *Set) *Set {
+	index := make(map[string]int)
+	merged := &Set{}
+
+	for _, e := range set.entries {
+		index[e.relativePath] = len(merged.entries)
+		merged.entries = append(merged.entries, e)
+	}
+
+	for _, e := range child.entries {
+		if i, ok := index[e.relativePath]; ok {
+			merged.entries[i] = e
+			continue
+		}
+		index[e.relativePath] = len(merged.entries)
+		merged.entries = append(merged.entries, e)
+	}
+
+	return merged
+}
+
+// isBinary treats content as binary when it has a NUL byte near the start,
+// as git does, or is not valid UTF-8.
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(content)
+}
+
+// parseString parses content as a template named after the file it came
+// from, so errors point at that file.
+func parseString(name, content string) (*template.Template, error) {
+	return expr.Parse(name, content)
+}
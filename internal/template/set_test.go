@@ -0,0 +1,106 @@
+package template
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func entryPaths(set *Set) []string {
+	var paths []string
+	for _, e := range set.entries {
+		paths = append(paths, filepath.ToSlash(e.relativePath))
+	}
+	return paths
+}
+
+func TestParseFSSkip(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		skip []string
+		want []string
+	}{
+		{
+			name: "nothing skipped",
+			fsys: fstest.MapFS{
+				"main.go": {Data: []byte("package main\n")},
+			},
+			want: []string{".", "main.go"},
+		},
+		{
+			name: "generator directories and their container",
+			fsys: fstest.MapFS{
+				"main.go":                          {Data: []byte("package main\n")},
+				"generators/handler/handler.go":    {Data: []byte("package handlers\n")},
+				"generators/handler/sub/routes.go": {Data: []byte("package sub\n")},
+				"generators/model/model.go":        {Data: []byte("package models\n")},
+			},
+			skip: []string{"generators/handler", "generators/model"},
+			want: []string{".", "main.go"},
+		},
+		{
+			name: "container with other files is kept",
+			fsys: fstest.MapFS{
+				"generators/README.md":          {Data: []byte("docs\n")},
+				"generators/handler/handler.go": {Data: []byte("package handlers\n")},
+			},
+			skip: []string{"generators/handler"},
+			want: []string{".", "generators", "generators/README.md"},
+		},
+		{
+			name: "nested container",
+			fsys: fstest.MapFS{
+				"main.go":                  {Data: []byte("package main\n")},
+				"gen/files/handler/a.go":   {Data: []byte("package a\n")},
+				"gen/files/handler/b/b.go": {Data: []byte("package b\n")},
+			},
+			skip: []string{"gen/files/handler"},
+			want: []string{".", "main.go"},
+		},
+		{
+			name: "prefix of a name is not a parent",
+			fsys: fstest.MapFS{
+				"generators/handler/a.go":   {Data: []byte("package a\n")},
+				"generators/handlers/b.go":  {Data: []byte("package b\n")},
+				"generators/handler.go.txt": {Data: []byte("text\n")},
+			},
+			skip: []string{"generators/handler"},
+			want: []string{".", "generators", "generators/handler.go.txt", "generators/handlers", "generators/handlers/b.go"},
+		},
+		{
+			name: "empty directories in the template are kept",
+			fsys: fstest.MapFS{
+				"empty":                   {Mode: fs.ModeDir | 0755},
+				"generators/handler/a.go": {Data: []byte("package a\n")},
+			},
+			skip: []string{"generators/handler"},
+			want: []string{".", "empty"},
+		},
+		{
+			name: "skipped file",
+			fsys: fstest.MapFS{
+				"tests/basic.toml": {Data: []byte("")},
+				"main.go":          {Data: []byte("package main\n")},
+			},
+			skip: []string{"tests/basic.toml"},
+			want: []string{".", "main.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := ParseFS(tt.fsys, config.Rules{Skip: tt.skip})
+			if err != nil {
+				t.Fatalf("ParseFS() error = %v", err)
+			}
+			if got := entryPaths(set); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
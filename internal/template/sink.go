@@ -0,0 +1,283 @@
+package template
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives generated output. Paths are relative to the output root.
+// WriteFile may be called from several goroutines with distinct paths;
+// nothing needs to be visible outside the sink until Commit.
+type Sink interface {
+	// ReadFile returns what is currently at path, so unchanged files can be
+	// skipped. Sinks without previous content return fs.ErrNotExist.
+	ReadFile(path string) ([]byte, error)
+	Mkdir(path string) error
+	WriteFile(path string, content []byte) error
+	Commit() error
+	// Close releases the sink. It is called after Commit or on failure.
+	Close() error
+}
+
+// MemorySink keeps generated files in memory. Reads fall back to base, when
+// set, so a dry run against os.DirFS(outputDir) reports overwritten and
+// unchanged files the way a real run would.
+type MemorySink struct {
+	base fs.FS
+
+	mu    sync.Mutex
+	dirs  []string
+	files map[string][]byte
+}
+
+func NewMemorySink(base fs.FS) *MemorySink {
+	return &MemorySink{base: base, files: make(map[string][]byte)}
+}
+
+func (sink *MemorySink) ReadFile(name string) ([]byte, error) {
+	sink.mu.Lock()
+	content, ok := sink.files[name]
+	sink.mu.Unlock()
+	if ok {
+		return content, nil
+	}
+
+	if sink.base == nil {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(sink.base, filepath.ToSlash(name))
+}
+
+func (sink *MemorySink) Mkdir(name string) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	sink.dirs = append(sink.dirs, name)
+	return nil
+}
+
+func (sink *MemorySink) WriteFile(name string, content []byte) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	sink.files[name] = content
+	return nil
+}
+
+func (sink *MemorySink) Commit() error { return nil }
+
+func (sink *MemorySink) Close() error { return nil }
+
+// Files returns the generated files keyed by output path.
+func (sink *MemorySink) Files() map[string][]byte {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	files := make(map[string][]byte, len(sink.files))
+	for name, content := range sink.files {
+		files[name] = content
+	}
+	return files
+}
+
+// Paths returns the generated file paths in sorted order.
+func (sink *MemorySink) Paths() []string {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	paths := make([]string, 0, len(sink.files))
+	for name := range sink.files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTar   ArchiveFormat = "tar"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// ArchiveFormatFor picks the archive format from a file name.
+func ArchiveFormatFor(name string) (ArchiveFormat, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz, nil
+	}
+	return "", fmt.Errorf("unsupported archive '%s' (supported: .zip, .tar, .tar.gz, .tgz)", name)
+}
+
+// ArchiveSink packages the generated files into a zip or tar archive written
+// to w on Commit. Entries are sorted and carry a fixed timestamp, so the
+// same output always gives the same archive.
+type ArchiveSink struct {
+	*MemorySink
+	w      io.Writer
+	format ArchiveFormat
+}
+
+func NewArchiveSink(w io.Writer, format ArchiveFormat) *ArchiveSink {
+	return &ArchiveSink{MemorySink: NewMemorySink(nil), w: w, format: format}
+}
+
+func (sink *ArchiveSink) Commit() error {
+	names := sink.entryNames()
+
+	var err error
+	switch sink.format {
+	case ArchiveZip:
+		err = sink.writeZip(names)
+	case ArchiveTar:
+		err = sink.writeTar(sink.w, names)
+	case ArchiveTarGz:
+		gzipWriter := gzip.NewWriter(sink.w)
+		if err = sink.writeTar(gzipWriter, names); err == nil {
+			err = gzipWriter.Close()
+		}
+	default:
+		err = fmt.Errorf("unsupported archive format '%s'", sink.format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// entryNames lists every directory and file as slash-separated archive
+// names, directories ending in "/", parents before children.
+func (sink *ArchiveSink) entryNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	addDirs := func(name string) {
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if !seen[dir+"/"] {
+				seen[dir+"/"] = true
+				names = append(names, dir+"/")
+			}
+		}
+	}
+
+	for _, dir := range sink.dirs {
+		name := filepath.ToSlash(dir)
+		if name == "." || name == "" {
+			continue
+		}
+		addDirs(name + "/x")
+	}
+	for _, file := range sink.Paths() {
+		name := filepath.ToSlash(file)
+		addDirs(name)
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (sink *ArchiveSink) writeZip(names []string) error {
+	zipWriter := zip.NewWriter(sink.w)
+	for _, name := range names {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Unix(0, 0).UTC()}
+		if strings.HasSuffix(name, "/") {
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | 0755)
+		} else {
+			header.SetMode(0644)
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(name, "/") {
+			if _, err := writer.Write(sink.files[filepath.FromSlash(name)]); err != nil {
+				return err
+			}
+		}
+	}
+	return zipWriter.Close()
+}
+
+func (sink *ArchiveSink) writeTar(w io.Writer, names []string) error {
+	tarWriter := tar.NewWriter(w)
+	for _, name := range names {
+		header := &tar.Header{
+			Name:    name,
+			ModTime: time.Unix(0, 0),
+			Format:  tar.FormatPAX,
+		}
+		content := sink.files[filepath.FromSlash(name)]
+		if strings.HasSuffix(name, "/") {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Mode = 0644
+			header.Size = int64(len(content))
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tarWriter.Write(content); err != nil {
+				return err
+			}
+		}
+	}
+	return tarWriter.Close()
+}
+
+// StreamSink writes a single generated file to w on Commit. With an empty
+// name the template must generate exactly one file.
+type StreamSink struct {
+	*MemorySink
+	w    io.Writer
+	name string
+}
+
+func NewStreamSink(w io.Writer, name string) *StreamSink {
+	return &StreamSink{MemorySink: NewMemorySink(nil), w: w, name: filepath.FromSlash(name)}
+}
+
+func (sink *StreamSink) Commit() error {
+	paths := sink.Paths()
+
+	name := sink.name
+	if name == "" {
+		if len(paths) != 1 {
+			return fmt.Errorf("template generates %d files; choose one to stream (generated: %s)", len(paths), strings.Join(paths, ", "))
+		}
+		name = paths[0]
+	}
+
+	content, ok := sink.files[filepath.Clean(name)]
+	if !ok {
+		return fmt.Errorf("template does not generate '%s' (generated: %s)", filepath.ToSlash(name), strings.Join(paths, ", "))
+	}
+
+	if _, err := sink.w.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
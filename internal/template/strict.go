@@ -0,0 +1,127 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/expr"
+)
+
+// MissingKey is one reference to a variable that has no value.
+type MissingKey struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Key  string `json:"key"`
+}
+
+// MissingKeysError lists every undefined variable found in strict mode.
+type MissingKeysError struct {
+	Keys []MissingKey
+}
+
+func (e *MissingKeysError) Error() string {
+	lines := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		lines[i] = fmt.Sprintf("  %s:%d: %s", key.File, key.Line, key.Key)
+	}
+	return fmt.Sprintf("%d undefined variable(s) in strict mode:\n%s", len(e.Keys), strings.Join(lines, "\n"))
+}
+
+type strictJob struct {
+	source string
+	text   string
+	tmpl   *template.Template
+}
+
+// CheckMissing looks through every output path, file and action of set
+// and returns a *MissingKeysError naming each reference to an undefined
+// variable with its file and line. Nothing is rendered or written; other
+// errors are left for the real run to report.
+func (processor *Processor) CheckMissing(set *Set) error {
+	var jobs []strictJob
+	for _, e := range set.entries {
+		if e.ignored {
+			continue
+		}
+		jobs = append(jobs, strictJob{source: e.relativePath, text: e.pathText, tmpl: e.path})
+		if e.content != nil {
+			jobs = append(jobs, strictJob{source: e.relativePath, text: e.text, tmpl: e.content})
+		}
+	}
+
+	for _, action := range processor.template.Actions {
+		op, argument := action.Op()
+		texts := []string{action.File, action.Content}
+		if op == config.OpAppend || op == config.OpPrepend {
+			texts = []string{action.File, argument}
+		}
+		for _, text := range texts {
+			// Syntax errors are reported when the action runs
+			if tmpl, err := parseString(action.File, text); err == nil {
+				jobs = append(jobs, strictJob{source: action.File, text: text, tmpl: tmpl})
+			}
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		missing []MissingKey
+	)
+	processor.render(len(jobs), func(job int) error {
+		keys := processor.missingKeys(jobs[job])
+		mu.Lock()
+		missing = append(missing, keys...)
+		mu.Unlock()
+		return nil
+	})
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(missing, func(i, j int) bool {
+		if missing[i].File != missing[j].File {
+			return missing[i].File < missing[j].File
+		}
+		return missing[i].Line < missing[j].Line
+	})
+	return &MissingKeysError{Keys: missing}
+}
+
+// missingKeys returns every reference in the job's template to a variable
+// without a value, or to a key missing from a map variable, whether or not
+// the reference would be reached with the current values.
+func (processor *Processor) missingKeys(job strictJob) []MissingKey {
+	if job.tmpl.Tree == nil {
+		return nil
+	}
+
+	var missing []MissingKey
+	for _, reference := range expr.References(job.tmpl.Tree.Root) {
+		if key, ok := processor.missingKey(reference.Path); ok {
+			missing = append(missing, MissingKey{File: job.source, Line: reference.Line(job.text), Key: key})
+		}
+	}
+	return missing
+}
+
+// missingKey follows path through the variables and nested maps, returning
+// the part of it up to the first key that has no entry. Values other than
+// maps are not looked into.
+func (processor *Processor) missingKey(path []string) (string, bool) {
+	var value any = processor.variables
+	for i, name := range path {
+		values, ok := value.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if value, ok = values[name]; !ok {
+			return strings.Join(path[:i+1], "."), true
+		}
+	}
+	return "", false
+}
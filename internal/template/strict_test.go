@@ -0,0 +1,67 @@
+package template
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+)
+
+func TestCheckMissing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.md": {Data: []byte("# {{.name}}\n\n{{.projct_name}} by {{.author}}\n{{.projct_name}} again\n")},
+		"{{.dir}}/main.go": {Data: []byte("package main\n" +
+			"{{if .debug}}\n// {{.debug_level}}\n{{end}}\n" +
+			"// {{.config.port}} {{.config.host}}\n" +
+			"{{range .items}}{{.field}} {{$.missing_in_range}}{{end}}\n")},
+	}
+	tmpl := &config.Template{
+		Actions: []config.Action{{File: "README.md", Append: "{{.footer}}"}},
+	}
+	values := map[string]any{
+		"name":   "shop",
+		"author": nil,
+		"dir":    "cmd",
+		"debug":  false,
+		"config": map[string]any{"port": 80},
+		"items":  []any{map[string]any{"other": 1}},
+	}
+
+	set, err := ParseFS(fsys, tmpl.Rules)
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	err = NewProcessor(tmpl, values).CheckMissing(set)
+
+	var missing *MissingKeysError
+	if !errors.As(err, &missing) {
+		t.Fatalf("CheckMissing() error = %v, want a MissingKeysError", err)
+	}
+
+	want := []MissingKey{
+		{File: "README.md", Line: 1, Key: "footer"},
+		{File: "README.md", Line: 3, Key: "projct_name"},
+		{File: "README.md", Line: 4, Key: "projct_name"},
+		{File: "{{.dir}}/main.go", Line: 3, Key: "debug_level"},
+		{File: "{{.dir}}/main.go", Line: 5, Key: "config.host"},
+		{File: "{{.dir}}/main.go", Line: 6, Key: "missing_in_range"},
+	}
+	if !slices.Equal(missing.Keys, want) {
+		t.Errorf("CheckMissing() keys =\n%v\nwant\n%v", missing.Keys, want)
+	}
+}
+
+func TestCheckMissingNone(t *testing.T) {
+	fsys := fstest.MapFS{
+		"{{.name}}.txt": {Data: []byte("{{.name}} {{upper .name}}\n")},
+	}
+	set, err := ParseFS(fsys, config.Rules{})
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	if err := NewProcessor(&config.Template{}, map[string]any{"name": "x"}).CheckMissing(set); err != nil {
+		t.Errorf("CheckMissing() error = %v", err)
+	}
+}
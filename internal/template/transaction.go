@@ -0,0 +1,299 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	stagingPrefix  = ".tg-staging-"
+	stagedFilesDir = "files"
+	backupFilesDir = "backup"
+)
+
+// IsStagingDir reports whether name is the base name of a directory a
+// transaction stages files in.
+func IsStagingDir(name string) bool {
+	return strings.HasPrefix(name, stagingPrefix)
+}
+
+// transaction stages generated files outside the output directory and only
+// moves them into place once every file has been rendered successfully.
+type transaction struct {
+	outputDir  string
+	stagingDir string
+
+	// realOutputDir is outputDir with symlinks resolved, or empty when the
+	// directory does not exist yet and so cannot contain any links
+	realOutputDir string
+
+	mu     sync.Mutex
+	dirs   []string
+	files  []string
+	staged map[string]bool
+
+	createdDirs []string
+	committed   []committedFile
+}
+
+type committedFile struct {
+	target string
+	backup string
+}
+
+// NewDiskSink returns a sink that writes into outputDir on disk. Files are
+// staged next to outputDir and renamed into place on Commit; if any rename
+// fails, everything done so far is rolled back.
+func NewDiskSink(outputDir string) (Sink, error) {
+	return newTransaction(outputDir)
+}
+
+func newTransaction(outputDir string) (*transaction, error) {
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	// Stage next to the output so the final renames stay on one filesystem
+	parent, err := nearestExistingDir(absOutputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var realOutputDir string
+	if parent == absOutputDir {
+		if realOutputDir, err = filepath.EvalSymlinks(absOutputDir); err != nil {
+			return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+		}
+	}
+
+	stagingDir, err := os.MkdirTemp(parent, stagingPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	return &transaction{
+		outputDir:     absOutputDir,
+		stagingDir:    stagingDir,
+		realOutputDir: realOutputDir,
+		staged:        make(map[string]bool),
+	}, nil
+}
+
+func nearestExistingDir(path string) (string, error) {
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("'%s' is not a directory", path)
+			}
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to check output directory: %w", err)
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no existing parent directory for '%s'", path)
+		}
+		path = parent
+	}
+}
+
+func (tx *transaction) Mkdir(relativePath string) error {
+	if err := tx.checkTarget(relativePath); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.dirs = append(tx.dirs, relativePath)
+	return nil
+}
+
+// target returns where a file ends up in the output directory.
+func (tx *transaction) target(relativePath string) string {
+	return filepath.Join(tx.outputDir, relativePath)
+}
+
+// checkTarget refuses a path whose existing part leads through a symlink to
+// somewhere outside the output directory.
+func (tx *transaction) checkTarget(relativePath string) error {
+	if tx.realOutputDir == "" {
+		return nil
+	}
+	return checkInside(tx.outputDir, tx.realOutputDir, relativePath)
+}
+
+// ReadFile returns the staged content of a file written in this run, or
+// what is currently in the output directory.
+func (tx *transaction) ReadFile(relativePath string) ([]byte, error) {
+	tx.mu.Lock()
+	staged := tx.staged[relativePath]
+	tx.mu.Unlock()
+
+	if staged {
+		return os.ReadFile(filepath.Join(tx.stagingDir, stagedFilesDir, relativePath))
+	}
+	if err := tx.checkTarget(relativePath); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tx.target(relativePath))
+}
+
+// WriteFile writes a rendered file into the staging area and marks it to be
+// moved into place on commit.
+func (tx *transaction) WriteFile(relativePath string, content []byte) error {
+	if err := tx.checkTarget(relativePath); err != nil {
+		return err
+	}
+
+	stagedPath := filepath.Join(tx.stagingDir, stagedFilesDir, relativePath)
+
+	if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	if err := os.WriteFile(stagedPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to stage file %s: %w", relativePath, err)
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if !tx.staged[relativePath] {
+		tx.staged[relativePath] = true
+		tx.files = append(tx.files, relativePath)
+	}
+	return nil
+}
+
+// Commit moves every staged file into the output directory. If any step
+// fails, everything moved so far is rolled back before returning.
+func (tx *transaction) Commit() (err error) {
+	defer func() {
+		if err != nil {
+			tx.rollback()
+		}
+	}()
+
+	if err := tx.mkdirAll(tx.outputDir); err != nil {
+		return err
+	}
+
+	for _, dir := range tx.dirs {
+		if err := tx.mkdirAll(filepath.Join(tx.outputDir, dir)); err != nil {
+			return err
+		}
+	}
+
+	// Files are staged concurrently; commit them in a stable order
+	sort.Strings(tx.files)
+	for _, file := range tx.files {
+		if err := tx.commitFile(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *transaction) commitFile(relativePath string) error {
+	stagedPath := filepath.Join(tx.stagingDir, stagedFilesDir, relativePath)
+	target := tx.target(relativePath)
+
+	if err := tx.mkdirAll(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	var backup string
+	if info, err := os.Lstat(target); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("cannot overwrite directory %s with a file", target)
+		}
+
+		// Overwriting keeps the file's permissions, as os.WriteFile would
+		if info, err := os.Stat(target); err == nil {
+			if err := os.Chmod(stagedPath, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to set the mode of %s: %w", target, err)
+			}
+		}
+
+		backup = filepath.Join(tx.stagingDir, backupFilesDir, relativePath)
+		if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		if err := os.Rename(target, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", target, err)
+		}
+	}
+
+	if err := os.Rename(stagedPath, target); err != nil {
+		if backup != "" {
+			os.Rename(backup, target)
+		}
+		return fmt.Errorf("failed to write file %s: %w", target, err)
+	}
+
+	tx.committed = append(tx.committed, committedFile{target: target, backup: backup})
+	return nil
+}
+
+// mkdirAll creates path and any missing parents, remembering which
+// directories it created so a rollback can remove them again.
+func (tx *transaction) mkdirAll(path string) error {
+	var missing []string
+	for current := path; ; current = filepath.Dir(current) {
+		info, err := os.Stat(current)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("'%s' is not a directory", current)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check directory %s: %w", current, err)
+		}
+		missing = append(missing, current)
+
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", missing[i], err)
+		}
+		tx.createdDirs = append(tx.createdDirs, missing[i])
+	}
+
+	return nil
+}
+
+func (tx *transaction) rollback() {
+	for i := len(tx.committed) - 1; i >= 0; i-- {
+		file := tx.committed[i]
+		os.Remove(file.target)
+		if file.backup != "" {
+			os.Rename(file.backup, file.target)
+		}
+	}
+	tx.committed = nil
+
+	for i := len(tx.createdDirs) - 1; i >= 0; i-- {
+		os.Remove(tx.createdDirs[i])
+	}
+	tx.createdDirs = nil
+}
+
+// Close removes the staging area. It is safe to call after Commit or
+// rollback.
+func (tx *transaction) Close() error {
+	return os.RemoveAll(tx.stagingDir)
+}
@@ -0,0 +1,175 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestTransactionCommit(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	writeTestFile(t, filepath.Join(out, "existing.txt"), "old\n", 0644)
+
+	tx, err := newTransaction(out)
+	if err != nil {
+		t.Fatalf("newTransaction() error = %v", err)
+	}
+	defer tx.Close()
+
+	for name, content := range map[string]string{"existing.txt": "new\n", "sub/created.txt": "created\n"} {
+		if err := tx.WriteFile(filepath.FromSlash(name), []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := tx.Mkdir("empty"); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if got := readTestFile(t, filepath.Join(out, "existing.txt")); got != "new\n" {
+		t.Errorf("existing.txt = %q, want %q", got, "new\n")
+	}
+	if got := readTestFile(t, filepath.Join(out, "sub", "created.txt")); got != "created\n" {
+		t.Errorf("sub/created.txt = %q, want %q", got, "created\n")
+	}
+	if info, err := os.Stat(filepath.Join(out, "empty")); err != nil || !info.IsDir() {
+		t.Errorf("empty directory was not created: %v", err)
+	}
+
+	if err := tx.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(tx.stagingDir); !os.IsNotExist(err) {
+		t.Errorf("staging directory was left behind: %v", err)
+	}
+}
+
+func TestTransactionKeepsFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not kept on windows")
+	}
+
+	out := t.TempDir()
+	writeTestFile(t, filepath.Join(out, "run.sh"), "#!/bin/sh\n", 0755)
+	writeTestFile(t, filepath.Join(out, "secret.env"), "A=1\n", 0600)
+
+	tx, err := newTransaction(out)
+	if err != nil {
+		t.Fatalf("newTransaction() error = %v", err)
+	}
+	defer tx.Close()
+
+	for _, name := range []string{"run.sh", "secret.env"} {
+		if err := tx.WriteFile(name, []byte("changed\n")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	for name, want := range map[string]os.FileMode{"run.sh": 0755, "secret.env": 0600} {
+		info, err := os.Stat(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		// existing output directory, or one created by the commit
+		existing bool
+	}{
+		{name: "existing output directory", existing: true},
+		{name: "new output directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			out := filepath.Join(root, "new", "out")
+			if tt.existing {
+				out = filepath.Join(root, "out")
+				writeTestFile(t, filepath.Join(out, "b.txt"), "original\n", 0644)
+			}
+
+			tx, err := newTransaction(out)
+			if err != nil {
+				t.Fatalf("newTransaction() error = %v", err)
+			}
+			defer tx.Close()
+
+			for _, name := range []string{"a/deep/new.txt", "b.txt", "z.txt"} {
+				if err := tx.WriteFile(filepath.FromSlash(name), []byte("generated\n")); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			if err := tx.Mkdir("empty"); err != nil {
+				t.Fatalf("Mkdir() error = %v", err)
+			}
+
+			// Files are committed in order, so z.txt fails after the others moved
+			if err := os.Remove(filepath.Join(tx.stagingDir, stagedFilesDir, "z.txt")); err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(); err == nil {
+				t.Fatal("Commit() succeeded, want an error")
+			}
+			if err := tx.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if !tt.existing {
+				if _, err := os.Stat(filepath.Join(root, "new")); !os.IsNotExist(err) {
+					t.Errorf("created output directory was left behind: %v", err)
+				}
+				return
+			}
+
+			if got := readTestFile(t, filepath.Join(out, "b.txt")); got != "original\n" {
+				t.Errorf("b.txt = %q, want the original content", got)
+			}
+			entries, err := os.ReadDir(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				var names []string
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				t.Errorf("output directory holds %v, want only b.txt", names)
+			}
+		})
+	}
+}
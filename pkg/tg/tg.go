@@ -0,0 +1,436 @@
+// Package tg generates files from tg templates. It is the API the tg command
+// is built on, and works over any fs.FS. The tg binary ships no templates of
+// its own, but other programs can embed theirs:
+//
+//	//go:embed templates
+//	var templates embed.FS
+//
+//	tmpl, err := tg.Open(templates, "web-app")
+//	values, err := tmpl.Resolve(map[string]any{"project_name": "shop"})
+//	result, err := tmpl.Apply("./shop", values)
+package tg
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+
+	"github.com/Naviary-Sanctuary/template_generator/internal/config"
+	"github.com/Naviary-Sanctuary/template_generator/internal/template"
+)
+
+type (
+	// Config is the content of a template's template.toml.
+	Config     = config.Template
+	Variable   = config.Variable
+	Rules      = config.Rules
+	Hooks      = config.Hooks
+	Result     = template.ProcessResult
+	FileResult = template.FileResult
+	Action     = template.Action
+
+	// PatchAction edits a file already in the output directory.
+	PatchAction = config.Action
+
+	// Migration upgrades a generated project between template versions;
+	// see Template.Migrate.
+	Migration       = config.Migration
+	MigrationStep   = config.MigrationStep
+	MigrationResult = template.MigrationResult
+	// MigrationProgress is told about each step Migrate runs.
+	MigrationProgress = template.MigrationProgress
+
+	// MissingKeysError lists the undefined variables found in strict mode.
+	MissingKeysError = template.MissingKeysError
+	MissingKey       = template.MissingKey
+
+	// Location, ParseError, ExecError and PathError describe template
+	// errors by file, line and column; see ErrorLocation.
+	Location   = template.Location
+	ParseError = template.ParseError
+	ExecError  = template.ExecError
+	PathError  = template.PathError
+
+	// UnsafePathError is returned when a rendered path would be written
+	// outside the output directory or into .git.
+	UnsafePathError = template.UnsafePathError
+
+	// Sink receives generated output; see NewDiskSink, NewMemorySink,
+	// NewArchiveSink and NewStreamSink.
+	Sink          = template.Sink
+	MemorySink    = template.MemorySink
+	ArchiveSink   = template.ArchiveSink
+	StreamSink    = template.StreamSink
+	ArchiveFormat = template.ArchiveFormat
+)
+
+const (
+	ActionCreated     = template.ActionCreated
+	ActionOverwritten = template.ActionOverwritten
+	ActionSkipped     = template.ActionSkipped
+	ActionCopied      = template.ActionCopied
+	ActionIgnored     = template.ActionIgnored
+	ActionInjected    = template.ActionInjected
+	ActionAppended    = template.ActionAppended
+	ActionPrepended   = template.ActionPrepended
+	ActionReplaced    = template.ActionReplaced
+)
+
+const (
+	ArchiveZip   = template.ArchiveZip
+	ArchiveTar   = template.ArchiveTar
+	ArchiveTarGz = template.ArchiveTarGz
+)
+
+// ErrorLocation returns the file, line and column of a template error
+// anywhere in err's chain.
+func ErrorLocation(err error) (Location, bool) {
+	return template.ErrorLocation(err)
+}
+
+// NewDiskSink writes into outputDir, staging files and moving them into
+// place only on commit.
+func NewDiskSink(outputDir string) (Sink, error) {
+	return template.NewDiskSink(outputDir)
+}
+
+// IsStagingDir reports whether name is the base name of a temporary
+// directory a disk sink stages files in.
+func IsStagingDir(name string) bool {
+	return template.IsStagingDir(name)
+}
+
+// NewMemorySink keeps generated files in memory. Existing content is read
+// from base, which may be nil.
+func NewMemorySink(base fs.FS) *MemorySink {
+	return template.NewMemorySink(base)
+}
+
+// NewArchiveSink writes the generated files to w as an archive on commit.
+func NewArchiveSink(w io.Writer, format ArchiveFormat) *ArchiveSink {
+	return template.NewArchiveSink(w, format)
+}
+
+// NewStreamSink writes the generated file name to w on commit. An empty name
+// requires the template to generate exactly one file.
+func NewStreamSink(w io.Writer, name string) *StreamSink {
+	return template.NewStreamSink(w, name)
+}
+
+// ArchiveFormatFor picks an archive format from a file name.
+func ArchiveFormatFor(name string) (ArchiveFormat, error) {
+	return template.ArchiveFormatFor(name)
+}
+
+// ConfigFile is the name of the file that marks a template directory.
+const ConfigFile = config.TemplateConfigFile
+
+// Template is a loaded template, possibly layered over the templates it
+// extends. It is parsed once and can be planned or applied many times.
+type Template struct {
+	// Config is the effective configuration after extends is applied.
+	Config *Config
+
+	chain   []*Config
+	layers  []fs.FS
+	workers int
+	strict  bool
+	set     *template.Set
+}
+
+// New wraps an already loaded config and the files it describes.
+func New(cfg *Config, fsys fs.FS) *Template {
+	return &Template{
+		Config:  cfg,
+		chain:   []*Config{cfg},
+		layers:  []fs.FS{fsys},
+		workers: runtime.GOMAXPROCS(0),
+	}
+}
+
+// Load reads the template at the root of fsys. Its extends field is not
+// followed; use Open or Extend for that.
+func Load(fsys fs.FS) (*Template, error) {
+	cfg, err := config.LoadTemplateFS(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, fsys), nil
+}
+
+// LoadDir is Load for a directory on disk. A template without a metadata
+// name is named after its directory.
+func LoadDir(dir string) (*Template, error) {
+	cfg, err := config.LoadTemplate(dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, os.DirFS(dir)), nil
+}
+
+// Open loads the template called name from fsys, which holds one directory
+// per template, the way a templates directory does. The template is found by
+// directory name, then by metadata name, and its extends chain is resolved
+// within fsys.
+func Open(fsys fs.FS, name string) (*Template, error) {
+	tmpl, err := openOne(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{tmpl.Name(): true}
+	for current := tmpl.chain[0]; current.Extends != ""; current = tmpl.chain[0] {
+		if seen[current.Extends] {
+			return nil, fmt.Errorf("template '%s' has a circular extends chain through '%s'", name, current.Extends)
+		}
+		seen[current.Extends] = true
+
+		parent, err := openOne(fsys, current.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("template '%s' extends '%s': %w", current.Metadata.Name, current.Extends, err)
+		}
+		tmpl = tmpl.Extend(parent)
+	}
+
+	return tmpl, nil
+}
+
+func openOne(fsys fs.FS, name string) (*Template, error) {
+	if _, err := fs.Stat(fsys, path.Join(name, ConfigFile)); err == nil {
+		return loadSub(fsys, name)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(fsys, path.Join(entry.Name(), ConfigFile)); err != nil {
+			continue
+		}
+		tmpl, err := loadSub(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if tmpl.Name() == name {
+			return tmpl, nil
+		}
+	}
+
+	return nil, fmt.Errorf("template '%s' not found", name)
+}
+
+func loadSub(fsys fs.FS, dir string) (*Template, error) {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := Load(sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", dir, err)
+	}
+	if tmpl.Config.Metadata.Name == "" {
+		tmpl.Config.Metadata.Name = path.Base(dir)
+	}
+	return tmpl, nil
+}
+
+// Extend returns t layered over parent: t's variables, rules and hooks win
+// or are appended, and its files replace the parent's at the same path.
+func (t *Template) Extend(parent *Template) *Template {
+	return &Template{
+		Config:  t.Config.Merge(parent.Config),
+		chain:   append(append([]*Config{}, parent.chain...), t.chain...),
+		layers:  append(append([]fs.FS{}, parent.layers...), t.layers...),
+		workers: t.workers,
+		strict:  t.strict,
+	}
+}
+
+func (t *Template) Name() string {
+	return t.Config.Metadata.Name
+}
+
+// Generator returns the named generator as a template of its own: its
+// variables, the files in its directory and its actions. A
+// generator without a files directory only patches existing files.
+func (t *Template) Generator(name string) (*Template, error) {
+	cfg, err := t.Config.Generator(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := t.Config.Generators[name].FilesDir(name)
+	generator := &Template{
+		Config:  cfg,
+		chain:   []*Config{cfg},
+		workers: t.workers,
+		strict:  t.strict,
+	}
+	for _, layer := range t.layers {
+		if info, err := fs.Stat(layer, dir); err != nil || !info.IsDir() {
+			continue
+		}
+		sub, err := fs.Sub(layer, dir)
+		if err != nil {
+			return nil, err
+		}
+		generator.layers = append(generator.layers, sub)
+	}
+
+	return generator, nil
+}
+
+// Chain returns the configs of every template in the extends chain, from
+// the base template to this one.
+func (t *Template) Chain() []*Config {
+	return t.chain
+}
+
+// SetWorkers limits how many files Apply renders concurrently.
+func (t *Template) SetWorkers(workers int) {
+	t.workers = workers
+}
+
+// SetStrict makes undefined variables an error, as strict = true in
+// template.toml does. A strict Apply fails with a *MissingKeysError before
+// anything is written.
+func (t *Template) SetStrict(strict bool) {
+	t.strict = strict
+}
+
+// Resolve computes the variable values for a run: the template's defaults,
+// overridden by each of layers in order, then checked against the variable
+// constraints.
+func (t *Template) Resolve(layers ...map[string]any) (map[string]any, error) {
+	values := make(map[string]any)
+	for name, variable := range t.Config.Variables {
+		values[name] = variable.Default
+	}
+
+	for _, layer := range layers {
+		for key, value := range layer {
+			values[key] = value
+		}
+	}
+
+	if err := t.Config.ValidateValues(values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Parse parses every file and output path of the template, reporting
+// template syntax errors without rendering anything.
+func (t *Template) Parse() error {
+	_, err := t.parse()
+	return err
+}
+
+func (t *Template) parse() (*template.Set, error) {
+	if t.set != nil {
+		return t.set, nil
+	}
+
+	rules := t.Config.Rules
+	rules.Skip = append(append([]string{}, rules.Skip...), t.Config.GeneratorDirs()...)
+
+	set := &template.Set{}
+	for i, layer := range t.layers {
+		layerRules := rules
+		// A generator's layers share one config, which has no tests
+		if len(t.chain) == len(t.layers) {
+			layerRules.Skip = append(append([]string{}, rules.Skip...), t.chain[i].TestPaths()...)
+		}
+
+		parsed, err := template.ParseFS(layer, layerRules)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			set = parsed
+		} else {
+			set = set.Overlay(parsed)
+		}
+	}
+
+	t.set = set
+	return set, nil
+}
+
+// Plan lists the files Apply would generate with values, without rendering
+// any content or touching the disk.
+func (t *Template) Plan(values map[string]any) ([]FileResult, error) {
+	set, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	return t.processor(values).Plan(set)
+}
+
+// Check looks through every path, file and action and returns a
+// *MissingKeysError listing each reference to a variable missing from
+// values, with its file and line.
+func (t *Template) Check(values map[string]any) error {
+	set, err := t.parse()
+	if err != nil {
+		return err
+	}
+	return t.processor(values).CheckMissing(set)
+}
+
+// Apply renders the template with values into outputDir. Files are staged
+// and moved into place only when every file has rendered, so a failed Apply
+// leaves outputDir untouched. Hooks are not run; see RunHooks.
+func (t *Template) Apply(outputDir string, values map[string]any) (*Result, error) {
+	set, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	return t.processor(values).ProcessSet(set, outputDir)
+}
+
+// ApplyTo renders the template with values into sink, such as an archive
+// or an in-memory sink for a dry run.
+func (t *Template) ApplyTo(sink Sink, values map[string]any) (*Result, error) {
+	set, err := t.parse()
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return t.processor(values).ProcessTo(set, sink)
+}
+
+// RunHooks renders commands with values and runs them through the system
+// shell in dir, stopping at the first failure.
+func (t *Template) RunHooks(commands []string, dir string, values map[string]any, stdout, stderr io.Writer) error {
+	return t.processor(values).RunHooks(commands, dir, stdout, stderr)
+}
+
+// Migrate runs migrations in the generated project dir, whose variables
+// are values. It returns the steps that ran and the values with variable
+// renames applied, ready to Resolve and Apply the new version with.
+//
+// Steps change the project directly. To resume an upgrade that failed
+// part way, record progress, which is called after every step, and pass
+// how many steps of the first migration already ran as skip.
+func (t *Template) Migrate(dir string, migrations []Migration, values map[string]any, skip int, progress MigrationProgress, stdout, stderr io.Writer) ([]MigrationResult, map[string]any, error) {
+	processor := t.processor(values)
+	results, err := processor.Migrate(dir, migrations, skip, progress, stdout, stderr)
+	return results, processor.Variables(), err
+}
+
+func (t *Template) processor(values map[string]any) *template.Processor {
+	processor := template.NewProcessor(t.Config, values)
+	processor.SetWorkers(t.workers)
+	if t.strict {
+		processor.SetStrict(true)
+	}
+	return processor
+}
@@ -0,0 +1,188 @@
+package tg
+
+import (
+	"embed"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/templates
+var embedded embed.FS
+
+func embeddedTemplates(t *testing.T) fs.FS {
+	t.Helper()
+	templates, err := fs.Sub(embedded, "testdata/templates")
+	if err != nil {
+		t.Fatalf("fs.Sub() error = %v", err)
+	}
+	return templates
+}
+
+func TestOpenEmbedded(t *testing.T) {
+	templates := embeddedTemplates(t)
+
+	for _, name := range []string{"greeter", "hello"} {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := Open(templates, name)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if got := tmpl.Name(); got != "hello" {
+				t.Errorf("Name() = %q, want %q", got, "hello")
+			}
+		})
+	}
+
+	if _, err := Open(templates, "missing"); err == nil {
+		t.Error("Open(missing) succeeded, want an error")
+	}
+}
+
+func TestApplyEmbedded(t *testing.T) {
+	tmpl, err := Open(embeddedTemplates(t), "hello")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	values, err := tmpl.Resolve(map[string]any{"name": "shop"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	plan, err := tmpl.Plan(values)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	var planned []string
+	for _, file := range plan {
+		planned = append(planned, filepath.ToSlash(file.Output))
+	}
+	slices.Sort(planned)
+	want := []string{"docs/README.md", "shop.txt"}
+	if !slices.Equal(planned, want) {
+		t.Errorf("Plan() outputs = %v, want %v", planned, want)
+	}
+
+	sink := NewMemorySink(nil)
+	if _, err := tmpl.ApplyTo(sink, values); err != nil {
+		t.Fatalf("ApplyTo() error = %v", err)
+	}
+	files := sink.Files()
+	if got := slices.Sorted(maps.Keys(files)); !slices.Equal(got, want) {
+		t.Errorf("ApplyTo() files = %v, want %v", got, want)
+	}
+	if got := string(files["shop.txt"]); got != "Hello, shop!\n" {
+		t.Errorf("shop.txt = %q", got)
+	}
+
+	dir := t.TempDir()
+	if _, err := tmpl.Apply(dir, values); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "docs", "README.md"))
+	if err != nil {
+		t.Fatalf("Apply() did not write docs/README.md: %v", err)
+	}
+	if got := string(content); got != "# Greeting for shop\n" {
+		t.Errorf("docs/README.md = %q", got)
+	}
+}
+
+func TestOpenExtends(t *testing.T) {
+	templates := fstest.MapFS{
+		"base/template.toml":   {Data: []byte("[metadata]\nname = \"base\"\n[variables]\nname = {required = true}\n")},
+		"base/README.md":       {Data: []byte("base {{.name}}\n")},
+		"base/main.go":         {Data: []byte("package {{.name}}\n")},
+		"web/template.toml":    {Data: []byte("extends = \"base\"\n[metadata]\nname = \"web\"\n[variables]\nport = {default = 8080}\n")},
+		"web/README.md":        {Data: []byte("web {{.name}} on {{.port}}\n")},
+		"loop-a/template.toml": {Data: []byte("extends = \"loop-b\"\n")},
+		"loop-b/template.toml": {Data: []byte("extends = \"loop-a\"\n")},
+	}
+
+	tmpl, err := Open(templates, "web")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	var chain []string
+	for _, cfg := range tmpl.Chain() {
+		chain = append(chain, cfg.Metadata.Name)
+	}
+	if want := []string{"base", "web"}; !slices.Equal(chain, want) {
+		t.Errorf("Chain() = %v, want %v", chain, want)
+	}
+
+	if _, err := tmpl.Resolve(); err == nil {
+		t.Error("Resolve() without the inherited required variable succeeded, want an error")
+	}
+	values, err := tmpl.Resolve(map[string]any{"name": "shop"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	sink := NewMemorySink(nil)
+	if _, err := tmpl.ApplyTo(sink, values); err != nil {
+		t.Fatalf("ApplyTo() error = %v", err)
+	}
+	files := sink.Files()
+	if got := string(files["README.md"]); got != "web shop on 8080\n" {
+		t.Errorf("README.md = %q, want the child's file", got)
+	}
+	if got := string(files["main.go"]); got != "package shop\n" {
+		t.Errorf("main.go = %q, want the parent's file", got)
+	}
+
+	if _, err := Open(templates, "loop-a"); err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Errorf("Open(loop-a) error = %v, want a circular extends error", err)
+	}
+}
+
+func TestApplySkipsTestsDir(t *testing.T) {
+	files := func(config string) fstest.MapFS {
+		return fstest.MapFS{
+			"app/template.toml":                 {Data: []byte(config)},
+			"app/main.go":                       {Data: []byte("package main\n")},
+			"app/tests/fixtures.toml":           {Data: []byte("[[fixture]]\n")},
+			"app/tests/basic/expected/main.go":  {Data: []byte("package main\n")},
+			"app/golden/basic.toml":             {Data: []byte("[variables]\n")},
+			"app/golden/basic/expected/main.go": {Data: []byte("package main\n")},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "without tests",
+			config: "[metadata]\nname = \"app\"\n",
+			want:   []string{"golden/basic.toml", "golden/basic/expected/main.go", "main.go", "tests/basic/expected/main.go", "tests/fixtures.toml"},
+		},
+		{
+			name:   "with a tests dir",
+			config: "[metadata]\nname = \"app\"\n[tests]\ndir = \"golden\"\n",
+			want:   []string{"main.go", "tests/basic/expected/main.go", "tests/fixtures.toml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Open(files(tt.config), "app")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			sink := NewMemorySink(nil)
+			if _, err := tmpl.ApplyTo(sink, map[string]any{}); err != nil {
+				t.Fatalf("ApplyTo() error = %v", err)
+			}
+			if got := slices.Sorted(maps.Keys(sink.Files())); !slices.Equal(got, tt.want) {
+				t.Errorf("ApplyTo() files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}